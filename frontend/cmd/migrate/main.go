@@ -0,0 +1,96 @@
+// Command migrate applies the versioned schema in db/migrations.
+//
+//	migrate up              apply all pending migrations
+//	migrate down [n]        roll back the last n migrations (default 1)
+//	migrate status          list migrations and when they were applied
+//	migrate baseline <ver>  mark migrations up to <ver> as applied without running them
+//
+// The database is taken from DATABASE_URL.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ElvanForge/lesson-forge/backend/db"
+	"github.com/jackc/pgx/v5"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() error {
+	return fmt.Errorf("usage: migrate up | down [n] | status | baseline <version>")
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usage()
+	}
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		return fmt.Errorf("DATABASE_URL is not set")
+	}
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	switch args[0] {
+	case "up":
+		ran, err := db.Up(ctx, conn)
+		report("applied", ran)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return usage()
+			}
+		}
+		ran, err := db.Down(ctx, conn, steps)
+		report("rolled back", ran)
+		return err
+	case "baseline":
+		if len(args) < 2 {
+			return usage()
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return usage()
+		}
+		marked, err := db.Baseline(ctx, conn, version)
+		report("marked", marked)
+		return err
+	case "status":
+		list, err := db.List(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+	return usage()
+}
+
+func report(verb string, ms []db.Migration) {
+	if len(ms) == 0 {
+		fmt.Println("nothing to do")
+	}
+	for _, m := range ms {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
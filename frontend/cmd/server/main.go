@@ -0,0 +1,92 @@
+// Command server runs the API as a long-lived HTTP server, for local
+// development and self-hosting. It serves the same routes as the Vercel
+// function and reads settings from the environment, then ../.env and .env.
+//
+// Run with -print-config to see the settings it picked up, secrets masked.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/ElvanForge/lesson-forge/backend/config"
+	"github.com/ElvanForge/lesson-forge/backend/router"
+)
+
+func main() {
+	printConfig := flag.Bool("print-config", false, "print the loaded configuration and exit")
+	flag.Parse()
+
+	router.SetupLogging(slog.LevelInfo)
+	cfg, err := config.Load("../.env", ".env")
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	router.SetupLogging(cfg.LogLevel)
+	if *printConfig {
+		cfg.Dump(os.Stdout)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := router.SetupTracing(ctx, cfg.OTLPEndpoint, cfg.ServiceName)
+	if err != nil {
+		fatal("tracing setup failed", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("flushing traces failed", "err", err)
+		}
+	}()
+
+	api, err := router.NewFromConfig(ctx, cfg)
+	if err != nil {
+		fatal("startup failed", err)
+	}
+	defer api.Close()
+
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           api,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("server failed", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("shutdown incomplete", "err", err)
+		}
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,299 @@
+// Package config loads the backend's settings from the environment and
+// optional .env files, applies defaults and validates them once at
+// startup, so a misconfigured deployment fails with a clear message
+// instead of on the first request that needs the missing value.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// Config holds every setting the backend reads. Fields are filled from the
+// variable named in their env tag; default is used when it is unset, and
+// secret values are masked by Dump ("url" masks only the password).
+type Config struct {
+	DatabaseURL  string        `env:"DATABASE_URL" required:"true" secret:"url"`
+	SupabaseURL  string        `env:"SUPABASE_URL" required:"true"`
+	JWTSecret    string        `env:"SUPABASE_JWT_SECRET" secret:"true"` // required unless SUPABASE_JWKS_URL is set
+	JWKSURL      string        `env:"SUPABASE_JWKS_URL"`                 // verify tokens with these keys instead
+	ServiceKey   string        `env:"SUPABASE_SERVICE_ROLE_KEY" secret:"true"`
+	PublicAppURL string        `env:"PUBLIC_APP_URL" default:"https://forge.vaelia.app"`
+	Origins      []string      `env:"ALLOWED_ORIGINS"` // defaults to PUBLIC_APP_URL
+	CORSMaxAge   time.Duration `env:"CORS_MAX_AGE" default:"10m"`
+	MetricsToken string        `env:"METRICS_TOKEN" secret:"true"`
+	AdminUserIDs []string      `env:"ADMIN_USER_IDS"` // may read the usage report and flagged requests
+
+	StripeWebhookSecret string `env:"STRIPE_WEBHOOK_SECRET" secret:"true"` // enables POST /api/webhooks/stripe
+	// TrustedProxies are the proxies (CIDRs or addresses, or * for any
+	// peer) whose X-Forwarded-For entries are believed. Empty uses the
+	// connection's address.
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+	InputScreenPolicy    string `env:"INPUT_SCREEN_POLICY" default:"sanitize"` // reject, sanitize or flag
+	ModerationClassifier bool   `env:"MODERATION_CLASSIFIER"`                  // second AI call to vet output
+	ModerationAttempts   int    `env:"MODERATION_ATTEMPTS" default:"2"`        // generations tried before blocking
+	ReadabilityRewrite   bool   `env:"READABILITY_REWRITE"`                    // rewrite lessons that miss their grade
+	ReadabilityTolerance int    `env:"READABILITY_TOLERANCE" default:"2"`      // grades off before rewriting
+
+	GeminiKey   string `env:"GEMINI_KEY" secret:"true"`
+	DeepSeekKey string `env:"DEEPSEEK_KEY" secret:"true"`
+	MockAI      bool   `env:"MOCK_AI"`
+	Location    string `env:"LOCATION"`
+
+	StorageBackend  string `env:"STORAGE_BACKEND" default:"supabase"`
+	StorageBucket   string `env:"STORAGE_BUCKET" default:"generated-files"`
+	S3Endpoint      string `env:"S3_ENDPOINT"`
+	S3Region        string `env:"S3_REGION" default:"us-east-1"`
+	S3AccessKey     string `env:"S3_ACCESS_KEY_ID"`
+	S3SecretKey     string `env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+	LocalDir        string `env:"LOCAL_STORAGE_DIR" default:"./output"`
+	LocalBaseURL    string `env:"LOCAL_STORAGE_BASE_URL" default:"/api/files"`
+	LocalSigningKey string `env:"LOCAL_STORAGE_SIGNING_KEY" secret:"true"`
+
+	LogLevel        slog.Level    `env:"LOG_LEVEL" default:"info"`
+	OTLPEndpoint    string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName     string        `env:"OTEL_SERVICE_NAME" default:"lesson-forge-api"`
+	Port            int           `env:"PORT" default:"8080"`
+	ReadTimeout     time.Duration `env:"SERVER_READ_TIMEOUT" default:"15s"`
+	WriteTimeout    time.Duration `env:"SERVER_WRITE_TIMEOUT" default:"120s"`
+	IdleTimeout     time.Duration `env:"SERVER_IDLE_TIMEOUT" default:"60s"`
+	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+}
+
+// Load reads the configuration from the process environment, falling back
+// to the given .env files (earlier files win; missing files are skipped),
+// and validates it. The returned error lists every problem found.
+func Load(files ...string) (*Config, error) {
+	fileVals := map[string]string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		if _, err := os.Stat(files[i]); err != nil {
+			continue
+		}
+		vals, err := godotenv.Read(files[i])
+		if err != nil {
+			return nil, fmt.Errorf("config: reading %s: %w", files[i], err)
+		}
+		for k, v := range vals {
+			fileVals[k] = v
+		}
+	}
+	return load(func(key string) string {
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+		return fileVals[key]
+	})
+}
+
+func load(lookup func(string) string) (*Config, error) {
+	c := &Config{}
+	var errs []error
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		name := f.Tag.Get("env")
+		raw := strings.TrimSpace(lookup(name))
+		if raw == "" {
+			if f.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("%s is required", name))
+				continue
+			}
+			raw = f.Tag.Get("default")
+		}
+		if raw == "" {
+			continue
+		}
+		if err := set(v.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	if len(c.Origins) == 0 {
+		c.Origins = []string{strings.TrimSuffix(c.PublicAppURL, "/")}
+	}
+	if len(errs) == 0 {
+		errs = c.validate()
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return c, nil
+}
+
+func set(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		field.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		field.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 30s or 2m", raw)
+		}
+		field.SetInt(int64(d))
+	case slog.Level:
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%q is not debug, info, warn or error", raw)
+		}
+		field.SetInt(int64(l))
+	case []string:
+		var list []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// validate checks settings that depend on each other or need more than
+// parsing.
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// pgx's parse error quotes the string back, so only say what's expected.
+	_, err := pgxpool.ParseConfig(c.DatabaseURL)
+	check(err == nil, "DATABASE_URL must be a postgres:// URL or key=value connection string")
+	check(isHTTPURL(c.SupabaseURL), "SUPABASE_URL must be an http(s) URL, got %q", c.SupabaseURL)
+	check(c.JWTSecret != "" || c.JWKSURL != "", "SUPABASE_JWT_SECRET is required unless SUPABASE_JWKS_URL is set")
+	check(c.JWKSURL == "" || isHTTPURL(c.JWKSURL), "SUPABASE_JWKS_URL must be an http(s) URL, got %q", c.JWKSURL)
+	check(isHTTPURL(c.PublicAppURL), "PUBLIC_APP_URL must be an http(s) URL, got %q", c.PublicAppURL)
+	for _, o := range c.Origins {
+		check(validOrigin(o), "ALLOWED_ORIGINS: %q must be *, or an origin like https://example.com or https://*.example.com", o)
+	}
+
+	check(c.CORSMaxAge >= 0, "CORS_MAX_AGE must not be negative")
+	for _, p := range c.TrustedProxies {
+		check(validProxy(p), "TRUSTED_PROXIES: %q must be *, a CIDR like 10.0.0.0/8 or an IP address", p)
+	}
+
+	check(slices.Contains(screenPolicies, c.InputScreenPolicy), "INPUT_SCREEN_POLICY must be reject, sanitize or flag, got %q", c.InputScreenPolicy)
+
+	check(c.ModerationAttempts >= 1, "MODERATION_ATTEMPTS must be at least 1")
+	check(c.ReadabilityTolerance >= 0, "READABILITY_TOLERANCE must not be negative")
+
+	check(c.MockAI || c.GeminiKey != "", "GEMINI_KEY is required unless MOCK_AI=true")
+
+	switch c.StorageBackend {
+	case "supabase":
+		check(c.ServiceKey != "", "SUPABASE_SERVICE_ROLE_KEY is required for STORAGE_BACKEND=supabase")
+	case "s3":
+		check(isHTTPURL(c.S3Endpoint), "S3_ENDPOINT must be an http(s) URL for STORAGE_BACKEND=s3")
+		check(c.S3AccessKey != "" && c.S3SecretKey != "", "S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for STORAGE_BACKEND=s3")
+	case "local":
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND must be supabase, s3 or local, got %q", c.StorageBackend))
+	}
+
+	check(c.OTLPEndpoint == "" || isHTTPURL(c.OTLPEndpoint), "OTEL_EXPORTER_OTLP_ENDPOINT must be an http(s) URL, got %q", c.OTLPEndpoint)
+
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535")
+	for name, d := range map[string]time.Duration{
+		"SERVER_READ_TIMEOUT":     c.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":    c.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":     c.IdleTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT": c.ShutdownTimeout,
+	} {
+		check(d > 0, "%s must be positive", name)
+	}
+	return errs
+}
+
+// screenPolicies are the safety.Policy values. config is imported by
+// everything else, so it names them instead of importing safety.
+var screenPolicies = []string{"reject", "sanitize", "flag"}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validProxy(p string) bool {
+	if p == "*" {
+		return true
+	}
+	if _, err := netip.ParsePrefix(p); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(p)
+	return err == nil
+}
+
+func validOrigin(o string) bool {
+	if o == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(o, "*.", "wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		(u.Path == "" || u.Path == "/") && u.RawQuery == "" && !strings.Contains(u.Host, "*")
+}
+
+// dsnPassword matches the password in a key=value connection string,
+// quoted or not.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactConnString masks the password in a postgres:// URL or key=value
+// connection string.
+func redactConnString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}xxxxx")
+}
+
+// Dump writes every setting as NAME=value with secrets masked, for
+// checking what a deployment actually picked up.
+func (c *Config) Dump(w io.Writer) {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		val := fmt.Sprint(v.Field(i).Interface())
+		if list, ok := v.Field(i).Interface().([]string); ok {
+			val = strings.Join(list, ",")
+		}
+		switch f.Tag.Get("secret") {
+		case "true":
+			if val != "" {
+				val = "[redacted]"
+			}
+		case "url":
+			val = redactConnString(val)
+		}
+		fmt.Fprintf(w, "%s=%s\n", f.Tag.Get("env"), val)
+	}
+}
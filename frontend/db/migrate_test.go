@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestMigrationsEmbedded(t *testing.T) {
+	ms, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions must be contiguous, want %d", m.Version, m.Name, i+1)
+		}
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {"m/0001_a.up.sql": {Data: []byte("SELECT 1")}},
+		"bad version":  {"m/x_a.up.sql": {Data: []byte("SELECT 1")}, "m/x_a.down.sql": {Data: []byte("SELECT 1")}},
+		"bad suffix":   {"m/0001_a.sql": {Data: []byte("SELECT 1")}},
+		"name clash":   {"m/0001_a.up.sql": {Data: []byte("SELECT 1")}, "m/0001_b.down.sql": {Data: []byte("SELECT 1")}},
+	}
+	for name, fsys := range cases {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestUpDown runs every migration up, all the way down and up again
+// against a scratch database. Point TEST_DATABASE_URL at an empty local
+// Postgres to run it, e.g.
+//
+//	docker run --rm -e POSTGRES_PASSWORD=pg -p 5432:5432 postgres:16
+//	TEST_DATABASE_URL=postgres://postgres:pg@localhost:5432/postgres go test ./db
+func TestUpDown(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+
+	all, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := Up(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(all) {
+		t.Fatalf("up ran %d migrations, want %d (is the database empty?)", len(ran), len(all))
+	}
+	if ran, err := Up(ctx, conn); err != nil || len(ran) != 0 {
+		t.Fatalf("second up: ran %d, err %v", len(ran), err)
+	}
+	assertApplied(t, ctx, conn, len(all))
+
+	// The schema has to actually work, not just apply.
+	if _, err := conn.Exec(ctx,
+		"INSERT INTO auth.users (id, email) VALUES ('00000000-0000-0000-0000-000000000001', 'a@example.com')"); err != nil {
+		t.Fatal(err)
+	}
+	var balance int
+	if err := conn.QueryRow(ctx,
+		"SELECT credit_balance FROM users WHERE email = 'a@example.com'").Scan(&balance); err != nil {
+		t.Fatalf("signup trigger did not create a profile: %v", err)
+	}
+	if balance != 0 {
+		t.Errorf("new users start with %d credits, want 0", balance)
+	}
+
+	if _, err := Down(ctx, conn, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertApplied(t, ctx, conn, len(all)-1)
+
+	ran, err = Down(ctx, conn, len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(all)-1 {
+		t.Fatalf("down ran %d migrations, want %d", len(ran), len(all)-1)
+	}
+	assertApplied(t, ctx, conn, 0)
+
+	if _, err := Up(ctx, conn); err != nil {
+		t.Fatalf("up after full down: %v", err)
+	}
+	assertApplied(t, ctx, conn, len(all))
+
+	t.Cleanup(func() {
+		Down(ctx, conn, len(all))
+		conn.Exec(ctx, "DELETE FROM auth.users WHERE email = 'a@example.com'")
+	})
+}
+
+func TestBaseline(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+
+	marked, err := Baseline(ctx, conn, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Exec(ctx, "DELETE FROM schema_migrations")
+	if len(marked) != 2 {
+		t.Fatalf("baseline marked %d migrations, want 2", len(marked))
+	}
+	assertApplied(t, ctx, conn, 2)
+}
+
+func assertApplied(t *testing.T, ctx context.Context, conn *pgx.Conn, want int) {
+	t.Helper()
+	list, err := List(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := 0
+	for _, s := range list {
+		if s.AppliedAt != nil {
+			got++
+		}
+	}
+	if got != want {
+		t.Errorf("%d migrations applied, want %d", got, want)
+	}
+}
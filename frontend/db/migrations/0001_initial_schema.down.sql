@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS on_auth_user_created ON auth.users;
+DROP FUNCTION IF EXISTS public.handle_new_user();
+DROP TABLE generations;
+DROP TABLE transactions;
+DROP TABLE users;
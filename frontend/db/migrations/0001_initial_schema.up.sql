@@ -0,0 +1,90 @@
+-- On plain Postgres (local dev, tests) stand in for the parts of Supabase's
+-- auth schema the policies below rely on. Real Supabase projects already
+-- have these and are left untouched.
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = 'auth') THEN
+    CREATE SCHEMA auth;
+    CREATE TABLE auth.users (id UUID PRIMARY KEY, email TEXT);
+    CREATE FUNCTION auth.uid() RETURNS UUID AS
+      'SELECT NULLIF(current_setting(''request.jwt.claim.sub'', true), '''')::uuid'
+      LANGUAGE sql STABLE;
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'authenticated') THEN
+    CREATE ROLE authenticated NOLOGIN;
+  END IF;
+END
+$$;
+
+-- Users table to track credit balance
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT UNIQUE NOT NULL,
+    credit_balance INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactions table for Stripe payments
+CREATE TABLE transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id),
+    stripe_session_id TEXT UNIQUE NOT NULL,
+    amount_cents INTEGER NOT NULL,
+    credits_added INTEGER NOT NULL,
+    status TEXT NOT NULL, -- 'pending', 'completed', 'failed'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- PPTX Generations log
+CREATE TABLE generations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id),
+    prompt TEXT NOT NULL,
+    file_path TEXT,
+    status TEXT NOT NULL, -- 'processing', 'completed', 'failed'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Enable RLS on all tables
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE generations ENABLE ROW LEVEL SECURITY;
+
+-- Users can only read their own profile.
+-- Users cannot INSERT or UPDATE their own balance; that is handled by the
+-- Go backend (service_role) or triggers.
+CREATE POLICY "Users can view own profile"
+ON users FOR SELECT
+TO authenticated
+USING (auth.uid() = id);
+
+-- Creates a profile on signup. New accounts start with 0 credits; credits
+-- come from purchases, promo codes and referrals.
+CREATE OR REPLACE FUNCTION public.handle_new_user()
+RETURNS trigger AS $$
+BEGIN
+  INSERT INTO public.users (id, email, credit_balance)
+  VALUES (new.id, new.email, 0);
+  RETURN new;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+CREATE TRIGGER on_auth_user_created
+  AFTER INSERT ON auth.users
+  FOR EACH ROW EXECUTE FUNCTION public.handle_new_user();
+
+CREATE POLICY "Users can view own generations"
+ON generations FOR SELECT
+TO authenticated
+USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own generations"
+ON generations FOR INSERT
+TO authenticated
+WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can view own transactions"
+ON transactions FOR SELECT
+TO authenticated
+USING (auth.uid() = user_id);
@@ -0,0 +1,6 @@
+DROP TABLE credit_ledger;
+ALTER TABLE transactions DROP COLUMN org_id;
+ALTER TABLE generations DROP COLUMN org_id;
+DROP TABLE organization_members;
+DROP FUNCTION IF EXISTS public.my_org_ids();
+DROP TABLE organizations;
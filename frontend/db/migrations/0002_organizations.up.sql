@@ -0,0 +1,62 @@
+-- Organizations (schools, departments) with a shared credit pool
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    credit_balance INTEGER NOT NULL DEFAULT 0 CHECK (credit_balance >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Membership, role and per-member spending limit (NULL = unlimited)
+CREATE TABLE organization_members (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'teacher')),
+    spend_limit INTEGER CHECK (spend_limit >= 0),
+    credits_spent INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX organization_members_user_idx ON organization_members (user_id);
+
+ALTER TABLE generations ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN org_id UUID REFERENCES organizations(id);
+
+-- Every credit movement (debit, refund, grant, transfer)
+CREATE TABLE credit_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    org_id UUID REFERENCES organizations(id) ON DELETE SET NULL,
+    delta INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX credit_ledger_user_idx ON credit_ledger (user_id, created_at);
+CREATE INDEX credit_ledger_org_idx ON credit_ledger (org_id, created_at);
+
+-- Members can see the organizations they belong to and their co-members.
+-- All writes go through the Go backend (service_role).
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE credit_ledger ENABLE ROW LEVEL SECURITY;
+
+-- SECURITY DEFINER so the membership lookup doesn't recurse into the
+-- organization_members policy below.
+CREATE OR REPLACE FUNCTION public.my_org_ids()
+RETURNS SETOF uuid AS $$
+  SELECT org_id FROM public.organization_members WHERE user_id = auth.uid();
+$$ LANGUAGE sql STABLE SECURITY DEFINER;
+
+CREATE POLICY "Members can view their organizations"
+ON organizations FOR SELECT
+TO authenticated
+USING (id IN (SELECT public.my_org_ids()));
+
+CREATE POLICY "Members can view co-members"
+ON organization_members FOR SELECT
+TO authenticated
+USING (org_id IN (SELECT public.my_org_ids()));
+
+CREATE POLICY "Users can view own ledger entries"
+ON credit_ledger FOR SELECT
+TO authenticated
+USING (auth.uid() = user_id);
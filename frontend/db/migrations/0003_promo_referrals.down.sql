@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN referral_rewarded_at;
+ALTER TABLE users DROP COLUMN referred_by;
+ALTER TABLE users DROP COLUMN referral_code;
+DROP TABLE promo_redemptions;
+DROP TABLE promo_codes;
@@ -0,0 +1,30 @@
+-- Promo codes are created by operators directly in the database
+CREATE TABLE promo_codes (
+    code TEXT PRIMARY KEY CHECK (code = upper(code)),
+    credits INTEGER NOT NULL CHECK (credits > 0),
+    max_redemptions INTEGER,   -- NULL = unlimited
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE promo_redemptions (
+    code TEXT NOT NULL REFERENCES promo_codes(code),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (code, user_id)
+);
+
+-- Referrals: both parties are rewarded on the referred user's first purchase
+ALTER TABLE users ADD COLUMN referral_code TEXT UNIQUE;
+ALTER TABLE users ADD COLUMN referred_by UUID REFERENCES users(id);
+ALTER TABLE users ADD COLUMN referral_rewarded_at TIMESTAMP WITH TIME ZONE;
+
+-- Codes are never readable by clients; redemptions are visible to the redeemer.
+ALTER TABLE promo_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE promo_redemptions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view own redemptions"
+ON promo_redemptions FOR SELECT
+TO authenticated
+USING (auth.uid() = user_id);
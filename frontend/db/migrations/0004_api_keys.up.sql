@@ -0,0 +1,16 @@
+-- API keys for programmatic access; only the SHA-256 of the key is stored.
+-- Key management goes through the Go backend; clients never read hashes.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX api_keys_user_idx ON api_keys (user_id);
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,2 @@
+DROP TABLE generation_slots;
+DROP TABLE rate_limits;
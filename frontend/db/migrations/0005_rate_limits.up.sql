@@ -0,0 +1,18 @@
+-- Token buckets shared by all serverless instances (keys like 'user:<id>', 'ip:<addr>')
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- In-flight generations, used to cap concurrency per user
+CREATE TABLE generation_slots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+CREATE INDEX generation_slots_user_idx ON generation_slots (user_id);
+
+-- Rate limiting state is backend-only
+ALTER TABLE rate_limits ENABLE ROW LEVEL SECURITY;
+ALTER TABLE generation_slots ENABLE ROW LEVEL SECURITY;
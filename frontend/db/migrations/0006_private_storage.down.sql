@@ -0,0 +1,7 @@
+DO $$
+BEGIN
+  IF to_regclass('storage.buckets') IS NOT NULL THEN
+    UPDATE storage.buckets SET public = true WHERE id = 'generated-files';
+  END IF;
+END
+$$;
@@ -0,0 +1,9 @@
+-- Generated files are private; clients get signed links from
+-- GET /api/generations/{id}/download instead of public URLs.
+DO $$
+BEGIN
+  IF to_regclass('storage.buckets') IS NOT NULL THEN
+    UPDATE storage.buckets SET public = false WHERE id = 'generated-files';
+  END IF;
+END
+$$;
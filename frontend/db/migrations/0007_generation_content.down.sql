@@ -0,0 +1,5 @@
+ALTER TABLE generations DROP COLUMN structure;
+ALTER TABLE generations DROP COLUMN raw_content;
+ALTER TABLE generations DROP COLUMN duration;
+ALTER TABLE generations DROP COLUMN grade;
+ALTER TABLE generations DROP COLUMN mode;
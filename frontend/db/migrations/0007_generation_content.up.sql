@@ -0,0 +1,6 @@
+-- Persist what was generated so files can be re-rendered without the AI
+ALTER TABLE generations ADD COLUMN mode TEXT;
+ALTER TABLE generations ADD COLUMN grade TEXT;
+ALTER TABLE generations ADD COLUMN duration TEXT;
+ALTER TABLE generations ADD COLUMN raw_content TEXT;
+ALTER TABLE generations ADD COLUMN structure JSONB;
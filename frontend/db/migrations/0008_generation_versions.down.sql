@@ -0,0 +1,2 @@
+DROP TABLE generation_versions;
+ALTER TABLE generations DROP COLUMN current_version;
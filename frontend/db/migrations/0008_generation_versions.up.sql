@@ -0,0 +1,21 @@
+-- Revision history: every edit or restore appends a version
+ALTER TABLE generations ADD COLUMN current_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE generation_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    generation_id UUID NOT NULL REFERENCES generations(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    structure JSONB,
+    file_path TEXT,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (generation_id, version)
+);
+
+ALTER TABLE generation_versions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view versions of own generations"
+ON generation_versions FOR SELECT
+TO authenticated
+USING (generation_id IN (SELECT id FROM generations WHERE user_id = auth.uid()));
@@ -0,0 +1,5 @@
+DROP INDEX generations_user_created_idx;
+ALTER TABLE generations DROP COLUMN search;
+ALTER TABLE generations DROP COLUMN tags;
+ALTER TABLE generations DROP COLUMN folder_id;
+DROP TABLE folders;
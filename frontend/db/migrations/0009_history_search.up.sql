@@ -0,0 +1,24 @@
+-- History organisation: folders, tags and full-text search
+CREATE TABLE folders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE generations ADD COLUMN folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+ALTER TABLE generations ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE generations ADD COLUMN search TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('english', prompt || ' ' || COALESCE(raw_content, ''))) STORED;
+
+CREATE INDEX generations_user_created_idx ON generations (user_id, created_at DESC);
+CREATE INDEX generations_search_idx ON generations USING GIN (search);
+CREATE INDEX generations_tags_idx ON generations USING GIN (tags);
+
+ALTER TABLE folders ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view own folders"
+ON folders FOR SELECT
+TO authenticated
+USING (auth.uid() = user_id);
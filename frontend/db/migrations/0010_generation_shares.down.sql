@@ -0,0 +1,2 @@
+ALTER TABLE generations DROP COLUMN copied_from;
+DROP TABLE generation_shares;
@@ -0,0 +1,22 @@
+-- View-only share links and copies made from them.
+-- Share links are resolved by the backend only.
+CREATE TABLE generation_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    generation_id UUID NOT NULL REFERENCES generations(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    views INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX generation_shares_generation_idx ON generation_shares (generation_id);
+
+ALTER TABLE generations ADD COLUMN copied_from UUID REFERENCES generations(id) ON DELETE SET NULL;
+
+ALTER TABLE generation_shares ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view own share links"
+ON generation_shares FOR SELECT
+TO authenticated
+USING (auth.uid() = created_by);
@@ -0,0 +1,18 @@
+DROP INDEX generation_versions_created_idx;
+DROP INDEX generations_created_idx;
+
+ALTER TABLE generation_versions
+    DROP COLUMN cost_usd,
+    DROP COLUMN latency_ms,
+    DROP COLUMN output_tokens,
+    DROP COLUMN input_tokens,
+    DROP COLUMN model,
+    DROP COLUMN provider;
+
+ALTER TABLE generations
+    DROP COLUMN cost_usd,
+    DROP COLUMN latency_ms,
+    DROP COLUMN output_tokens,
+    DROP COLUMN input_tokens,
+    DROP COLUMN model,
+    DROP COLUMN provider;
@@ -0,0 +1,20 @@
+-- AI usage and cost of the call that produced each generation, and of each
+-- section edit. Copies, restores and older rows leave these NULL.
+ALTER TABLE generations
+    ADD COLUMN provider TEXT,
+    ADD COLUMN model TEXT,
+    ADD COLUMN input_tokens INTEGER,
+    ADD COLUMN output_tokens INTEGER,
+    ADD COLUMN latency_ms INTEGER,
+    ADD COLUMN cost_usd NUMERIC(12, 6);
+
+ALTER TABLE generation_versions
+    ADD COLUMN provider TEXT,
+    ADD COLUMN model TEXT,
+    ADD COLUMN input_tokens INTEGER,
+    ADD COLUMN output_tokens INTEGER,
+    ADD COLUMN latency_ms INTEGER,
+    ADD COLUMN cost_usd NUMERIC(12, 6);
+
+CREATE INDEX generations_created_idx ON generations (created_at) WHERE model IS NOT NULL;
+CREATE INDEX generation_versions_created_idx ON generation_versions (created_at) WHERE model IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE generation_versions DROP COLUMN template_version;
+ALTER TABLE generations DROP COLUMN template_version;
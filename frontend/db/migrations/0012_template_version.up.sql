@@ -0,0 +1,4 @@
+-- Prompt template that produced each generation and each edited version,
+-- e.g. lesson.any.k-2.v1, for comparing template versions.
+ALTER TABLE generations ADD COLUMN template_version TEXT;
+ALTER TABLE generation_versions ADD COLUMN template_version TEXT;
@@ -0,0 +1,17 @@
+-- Requests the input screening stage flagged, kept for review. input holds
+-- the fields as submitted, before any sanitizing.
+CREATE TABLE flagged_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('rejected', 'sanitized', 'flagged')),
+    findings JSONB NOT NULL,
+    input JSONB NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX flagged_requests_open_idx ON flagged_requests (created_at) WHERE reviewed_at IS NULL;
+
+-- Review goes through the Go backend only
+ALTER TABLE flagged_requests ENABLE ROW LEVEL SECURITY;
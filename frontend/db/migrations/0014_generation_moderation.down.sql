@@ -0,0 +1,2 @@
+ALTER TABLE generation_versions DROP COLUMN moderation;
+ALTER TABLE generations DROP COLUMN moderation;
@@ -0,0 +1,6 @@
+-- Verdict of the output moderation check on the content each generation
+-- and version was saved with. Content that failed is never saved; it is
+-- recorded in flagged_requests with kind '<kind>_output' instead. Copies,
+-- restores, manual edits and older rows leave this NULL.
+ALTER TABLE generations ADD COLUMN moderation JSONB;
+ALTER TABLE generation_versions ADD COLUMN moderation JSONB;
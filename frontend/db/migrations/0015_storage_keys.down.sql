@@ -0,0 +1,3 @@
+-- The project host isn't known here, and bare keys work with either
+-- bucket setting, so there is nothing to undo.
+SELECT 1;
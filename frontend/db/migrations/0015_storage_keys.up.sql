@@ -0,0 +1,11 @@
+-- Files uploaded before private storage were saved as full public URLs
+-- (https://<project>.supabase.co/storage/v1/object/public/generated-files/<key>).
+-- The bucket is private now, so those links are dead; keep just the key
+-- and let the download endpoint sign it like any other file.
+UPDATE generations
+SET file_path = regexp_replace(file_path, '^https?://[^/]+/storage/v1/object/public/generated-files/', '')
+WHERE file_path ~ '^https?://[^/]+/storage/v1/object/public/generated-files/';
+
+UPDATE generation_versions
+SET file_path = regexp_replace(file_path, '^https?://[^/]+/storage/v1/object/public/generated-files/', '')
+WHERE file_path ~ '^https?://[^/]+/storage/v1/object/public/generated-files/';
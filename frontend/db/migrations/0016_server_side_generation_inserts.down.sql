@@ -0,0 +1,4 @@
+CREATE POLICY "Users can insert own generations"
+ON generations FOR INSERT
+TO authenticated
+WITH CHECK (auth.uid() = user_id);
@@ -0,0 +1,4 @@
+-- Generations are only written by the API, which moderates content and
+-- charges credits. The old client-side insert let any signed-in user store
+-- arbitrary content, which shared links then showed to others.
+DROP POLICY IF EXISTS "Users can insert own generations" ON generations;
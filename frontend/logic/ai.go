@@ -4,23 +4,43 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"net/url"
 	"time"
 )
 
 type AIProvider interface {
-	GenerateContent(ctx context.Context, prompt string, genImage bool) (string, error)
+	Name() string
+	GenerateContent(ctx context.Context, prompt string, genImage bool) (Completion, error)
+	// Check confirms the provider is reachable and accepts our key
+	// without generating anything, so it costs no tokens.
+	Check(ctx context.Context) error
 }
 
+// Completion is a provider's answer together with the token usage it
+// reported and how long the call took.
+type Completion struct {
+	Text         string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Latency      time.Duration
+}
+
+const geminiModel = "gemini-2.5-flash"
+
 // GeminiProvider implementation
 type GeminiProvider struct {
-	APIKey string
+	APIKey    string
+	Transport http.RoundTripper // nil uses http.DefaultTransport
 }
 
-func (g *GeminiProvider) GenerateContent(ctx context.Context, prompt string, genImage bool) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent?key=%s", g.APIKey)
+func (g *GeminiProvider) Name() string { return "gemini" }
+
+func (g *GeminiProvider) GenerateContent(ctx context.Context, prompt string, genImage bool) (Completion, error) {
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", geminiModel)
 
 	modalities := []string{"TEXT"}
 	if genImage {
@@ -39,11 +59,15 @@ func (g *GeminiProvider) GenerateContent(ctx context.Context, prompt string, gen
 	jsonData, _ := json.Marshal(payload)
 	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.APIKey)
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	client := &http.Client{Timeout: 120 * time.Second, Transport: g.Transport}
 	resp, err := client.Do(req)
-	if err != nil { return "", err }
+	if err != nil { return Completion{}, fmt.Errorf("gemini: %w", transportError(err)) }
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Completion{}, fmt.Errorf("gemini api error: %d", resp.StatusCode)
+	}
 
 	var result struct {
 		Candidates []struct {
@@ -51,25 +75,169 @@ func (g *GeminiProvider) GenerateContent(ctx context.Context, prompt string, gen
 				Parts []struct { Text string `json:"text"` } `json:"parts"`
 			} `json:"content"`
 		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+		ModelVersion string `json:"modelVersion"`
 	}
 	json.NewDecoder(resp.Body).Decode(&result)
-	
+
+	c := Completion{
+		Model:        result.ModelVersion,
+		InputTokens:  result.UsageMetadata.PromptTokenCount,
+		OutputTokens: result.UsageMetadata.CandidatesTokenCount,
+	}
+	if c.Model == "" { c.Model = geminiModel }
 	if len(result.Candidates) > 0 && len(result.Candidates[0].Content.Parts) > 0 {
-		return result.Candidates[0].Content.Parts[0].Text, nil
+		c.Text = result.Candidates[0].Content.Parts[0].Text
+		return c, nil
+	}
+	return c, fmt.Errorf("AI returned empty content")
+}
+
+// Check lists models, which needs a valid key but spends no tokens.
+func (g *GeminiProvider) Check(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1", nil)
+	req.Header.Set("x-goog-api-key", g.APIKey)
+	return checkStatus("gemini", g.Transport, req)
+}
+
+// DeepSeekProvider is used where Gemini is unavailable (mainland China).
+// It ignores genImage.
+type DeepSeekProvider struct {
+	APIKey    string
+	Transport http.RoundTripper // nil uses http.DefaultTransport
+}
+
+const deepSeekModel = "deepseek-chat"
+
+func (d *DeepSeekProvider) Name() string { return "deepseek" }
+
+func (d *DeepSeekProvider) GenerateContent(ctx context.Context, prompt string, genImage bool) (Completion, error) {
+	payload := map[string]interface{}{
+		"model": deepSeekModel,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": prompt},
+		},
+	}
+
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.deepseek.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+d.APIKey)
+
+	client := &http.Client{Timeout: 120 * time.Second, Transport: d.Transport}
+	resp, err := client.Do(req)
+	if err != nil { return Completion{}, fmt.Errorf("deepseek: %w", transportError(err)) }
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Completion{}, fmt.Errorf("deepseek api error: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct { Content string `json:"content"` } `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	c := Completion{
+		Model:        result.Model,
+		InputTokens:  result.Usage.PromptTokens,
+		OutputTokens: result.Usage.CompletionTokens,
+	}
+	if c.Model == "" { c.Model = deepSeekModel }
+	if len(result.Choices) > 0 && result.Choices[0].Message.Content != "" {
+		c.Text = result.Choices[0].Message.Content
+		return c, nil
+	}
+	return c, fmt.Errorf("AI returned empty content")
+}
+
+func (d *DeepSeekProvider) Check(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.deepseek.com/models", nil)
+	req.Header.Set("Authorization", "Bearer "+d.APIKey)
+	return checkStatus("deepseek", d.Transport, req)
+}
+
+// checkStatus sends a provider health request and expects 200.
+func checkStatus(provider string, rt http.RoundTripper, req *http.Request) error {
+	client := &http.Client{Timeout: 10 * time.Second, Transport: rt}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", provider, transportError(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s api error: %d", provider, resp.StatusCode)
 	}
-	return "", fmt.Errorf("AI returned empty content")
+	return nil
+}
+
+// transportError drops the request URL that http.Client wraps around a
+// failed call, so errors that reach logs and traces never carry anything
+// a provider URL might hold, such as a key.
+func transportError(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
 }
 
-// FIX: Added missing MockProvider struct definition
 type MockProvider struct{}
 
-func (m *MockProvider) GenerateContent(ctx context.Context, p string, img bool) (string, error) {
-	return "# Mock Content\nThis is a generated lesson plan for testing purposes.", nil
+func (m *MockProvider) Name() string { return "mock" }
+
+func (m *MockProvider) Check(ctx context.Context) error { return nil }
+
+func (m *MockProvider) GenerateContent(ctx context.Context, p string, img bool) (Completion, error) {
+	return Completion{Model: "mock", Text: `# Mock Lesson
+## Objectives
+- This is a generated lesson plan for testing purposes.
+## Summary of Tasks
+- Key Point A
+- Key Point B`}, nil
+}
+
+// AIConfig holds the provider keys. Mock short-circuits every request to
+// MockProvider for cost-free testing.
+type AIConfig struct {
+	GeminiKey   string
+	DeepSeekKey string
+	Mock        bool
+	Location    string            // CN routes every request to DeepSeek
+	Transport   http.RoundTripper // for provider calls; nil uses http.DefaultTransport
 }
 
-func GetAIProvider(countryCode string) AIProvider {
-	if os.Getenv("MOCK_AI") == "true" {
+// Provider picks the provider for a request. countryCode is the caller's
+// ISO country (from Vercel's geo header); Location=CN forces the China
+// route for self-hosted deployments.
+func (c AIConfig) Provider(countryCode string) AIProvider {
+	if c.Mock {
 		return &MockProvider{}
 	}
-	return &GeminiProvider{APIKey: os.Getenv("GEMINI_KEY")}
-}
\ No newline at end of file
+	if c.DeepSeekKey != "" && (countryCode == "CN" || c.Location == "CN") {
+		return &DeepSeekProvider{APIKey: c.DeepSeekKey, Transport: c.Transport}
+	}
+	return &GeminiProvider{APIKey: c.GeminiKey, Transport: c.Transport}
+}
+
+// Providers lists every provider this configuration can route to, for
+// health checks.
+func (c AIConfig) Providers() []AIProvider {
+	if c.Mock {
+		return []AIProvider{&MockProvider{}}
+	}
+	ps := []AIProvider{&GeminiProvider{APIKey: c.GeminiKey, Transport: c.Transport}}
+	if c.DeepSeekKey != "" {
+		ps = append(ps, &DeepSeekProvider{APIKey: c.DeepSeekKey, Transport: c.Transport})
+	}
+	return ps
+}
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+const testKey = "AIzaSy-test-key"
+
+func TestProviderErrorsOmitKey(t *testing.T) {
+	var sent []*http.Request
+	failing := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = append(sent, r)
+		return nil, errors.New("dial tcp: connection refused")
+	})
+	providers := []AIProvider{
+		&GeminiProvider{APIKey: testKey, Transport: failing},
+		&DeepSeekProvider{APIKey: testKey, Transport: failing},
+	}
+	for _, p := range providers {
+		sent = nil
+		_, genErr := p.GenerateContent(context.Background(), "photosynthesis", false)
+		checkErr := p.Check(context.Background())
+		for name, err := range map[string]error{"GenerateContent": genErr, "Check": checkErr} {
+			if err == nil {
+				t.Errorf("%s %s: succeeded over a failing transport", p.Name(), name)
+				continue
+			}
+			if strings.Contains(err.Error(), testKey) {
+				t.Errorf("%s %s: error %q contains the API key", p.Name(), name, err)
+			}
+			if !strings.Contains(err.Error(), "connection refused") {
+				t.Errorf("%s %s: error %q lost the cause", p.Name(), name, err)
+			}
+		}
+		for _, r := range sent {
+			if strings.Contains(r.URL.String(), testKey) {
+				t.Errorf("%s: request URL %s carries the API key", p.Name(), r.URL)
+			}
+		}
+	}
+}
+
+func TestGeminiSendsKeyInHeader(t *testing.T) {
+	var got string
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header.Get("x-goog-api-key")
+		return nil, errors.New("stop")
+	})
+	(&GeminiProvider{APIKey: testKey, Transport: rt}).GenerateContent(context.Background(), "x", false)
+	if got != testKey {
+		t.Errorf("x-goog-api-key = %q, want the key", got)
+	}
+}
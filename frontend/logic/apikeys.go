@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// API key scopes. A key created without scopes may use all of them.
+// Anything that writes files or spends credits needs ScopeGenerate.
+const (
+	ScopeGenerate    = "generate"
+	ScopeReadHistory = "read:history"
+	ScopeReadCredits = "read:credits"
+)
+
+var AllScopes = []string{ScopeGenerate, ScopeReadHistory, ScopeReadCredits}
+
+// APIKeyPrefix marks bearer tokens that are API keys rather than JWTs.
+const APIKeyPrefix = "lf_"
+
+var ErrKeyNotFound = errors.New("api key not found")
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Can reports whether u may act with scope. Browser sessions can do
+// anything; API keys only what they were granted.
+func (u User) Can(scope string) bool {
+	if u.APIKeyID == "" {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidScopes(scopes []string) bool {
+	for _, s := range scopes {
+		known := false
+		for _, a := range AllScopes {
+			known = known || s == a
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAPIKey issues a new key for userID. The plaintext key is returned
+// only here; the database keeps its SHA-256 hash.
+func CreateAPIKey(ctx context.Context, db DB, userID, name string, scopes []string) (APIKey, string, error) {
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+	prefix, err := randomCode(8)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	secret, err := randomCode(32)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	plain := APIKeyPrefix + strings.ToLower(prefix) + "_" + secret
+
+	k := APIKey{Name: name, Prefix: APIKeyPrefix + strings.ToLower(prefix), Scopes: scopes}
+	err = db.QueryRow(ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1::uuid, $2, $3, $4, $5)
+		 RETURNING id, created_at`,
+		userID, name, k.Prefix, hashKey(plain), scopes).Scan(&k.ID, &k.CreatedAt)
+	return k, plain, err
+}
+
+func ListAPIKeys(ctx context.Context, db DB, userID string) ([]APIKey, error) {
+	rows, err := db.Query(ctx,
+		`SELECT id, name, prefix, scopes, last_used_at, created_at, revoked_at
+		 FROM api_keys WHERE user_id = $1::uuid ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) {
+		var k APIKey
+		err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.LastUsedAt, &k.CreatedAt, &k.RevokedAt)
+		return k, err
+	})
+}
+
+func RevokeAPIKey(ctx context.Context, db DB, userID, keyID string) error {
+	res, err := db.Exec(ctx,
+		"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1::uuid AND user_id = $2::uuid AND revoked_at IS NULL",
+		keyID, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext key to its owner and bumps the
+// key's last-used timestamp.
+func AuthenticateAPIKey(ctx context.Context, db DB, plain string) (User, error) {
+	var u User
+	err := db.QueryRow(ctx,
+		`UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		 WHERE key_hash = $1 AND revoked_at IS NULL
+		 RETURNING id, user_id, scopes`,
+		hashKey(plain)).Scan(&u.APIKeyID, &u.ID, &u.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrInvalidToken
+	}
+	u.Role = "api_key"
+	return u, err
+}
+
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
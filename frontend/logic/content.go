@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"strings"
+)
+
+// Section is one slide of a presentation or one heading of a lesson plan.
+type Section struct {
+	Title string   `json:"title"`
+	Lines []string `json:"lines"`
+}
+
+// Document is the parsed structure of AI output, stored alongside the raw
+// text so files can be re-rendered and individual sections edited.
+type Document struct {
+	Title    string    `json:"title"`
+	Mode     string    `json:"mode"`
+	Sections []Section `json:"sections"`
+}
+
+// ParseContent splits raw AI output into sections: slides separated by
+// "---" in ppt mode, "#"/"##" headings otherwise.
+func ParseContent(mode, content string) Document {
+	if mode == "ppt" {
+		return parseSlides(content)
+	}
+	return parseLesson(content)
+}
+
+func parseSlides(content string) Document {
+	doc := Document{Mode: "ppt"}
+	for _, chunk := range strings.Split(content, "---") {
+		var sec Section
+		for _, line := range strings.Split(chunk, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if sec.Title == "" {
+				sec.Title = strings.TrimSpace(strings.TrimLeft(line, "# "))
+			} else {
+				sec.Lines = append(sec.Lines, line)
+			}
+		}
+		if sec.Title != "" {
+			doc.Sections = append(doc.Sections, sec)
+		}
+	}
+	if len(doc.Sections) > 0 {
+		doc.Title = doc.Sections[0].Title
+	}
+	return doc
+}
+
+func parseLesson(content string) Document {
+	doc := Document{Mode: "lesson"}
+	var cur *Section
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "# ") && doc.Title == "":
+			doc.Title = strings.TrimSpace(trimmed[2:])
+		case strings.HasPrefix(trimmed, "## "):
+			doc.Sections = append(doc.Sections, Section{Title: strings.TrimSpace(trimmed[3:])})
+			cur = &doc.Sections[len(doc.Sections)-1]
+		case trimmed == "":
+		case cur == nil:
+			// Text before the first "##" goes into an untitled intro section.
+			doc.Sections = append(doc.Sections, Section{})
+			cur = &doc.Sections[len(doc.Sections)-1]
+			cur.Lines = append(cur.Lines, trimmed)
+		default:
+			cur.Lines = append(cur.Lines, trimmed)
+		}
+	}
+	return doc
+}
+
+// ParseSection reads a single slide or section returned by the AI when one
+// section is rewritten.
+func ParseSection(mode, text string) (Section, bool) {
+	doc := ParseContent(mode, text)
+	for _, sec := range doc.Sections {
+		if sec.Title != "" || len(sec.Lines) > 0 {
+			if sec.Title == "" {
+				sec.Title = doc.Title
+			}
+			return sec, true
+		}
+	}
+	if doc.Title != "" {
+		return Section{Title: doc.Title}, true
+	}
+	return Section{}, false
+}
+
+// Markdown turns a Document back into the text format ParseContent reads,
+// so edited structures can be rendered with the existing generators.
+func (d Document) Markdown() string {
+	var b strings.Builder
+	if d.Mode == "ppt" {
+		for i, sec := range d.Sections {
+			if i > 0 {
+				b.WriteString("\n---\n")
+			}
+			b.WriteString(sec.Title + "\n")
+			for _, l := range sec.Lines {
+				b.WriteString(l + "\n")
+			}
+		}
+		return b.String()
+	}
+	if d.Title != "" {
+		b.WriteString("# " + d.Title + "\n")
+	}
+	for _, sec := range d.Sections {
+		if sec.Title != "" {
+			b.WriteString("\n## " + sec.Title + "\n")
+		}
+		for _, l := range sec.Lines {
+			b.WriteString(l + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package logic
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrInsufficientCredits = errors.New("insufficient credits")
+	ErrSpendLimitReached   = errors.New("member spending limit reached")
+	ErrNotOrgMember        = errors.New("not a member of this organization")
+)
+
+// DB is satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx, so credit
+// operations can run standalone or inside a caller's transaction.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Account is the balance a credit movement applies to. When OrgID is set
+// the organization pool is charged and UserID is the member spending it.
+type Account struct {
+	UserID string
+	OrgID  string
+}
+
+// DebitCredits atomically removes amount credits from acct, recording the
+// movement in credit_ledger. Org debits also count against the member's
+// spending limit.
+func DebitCredits(ctx context.Context, db DB, acct Account, amount int, reason string) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if acct.OrgID == "" {
+			res, err := tx.Exec(ctx,
+				"UPDATE users SET credit_balance = credit_balance - $1 WHERE id = $2::uuid AND credit_balance >= $1",
+				amount, acct.UserID)
+			if err != nil {
+				return err
+			}
+			if res.RowsAffected() == 0 {
+				return ErrInsufficientCredits
+			}
+			return writeLedger(ctx, tx, acct, -amount, reason)
+		}
+
+		var allowed bool
+		err := tx.QueryRow(ctx,
+			`UPDATE organization_members SET credits_spent = credits_spent + $1
+			 WHERE org_id = $2::uuid AND user_id = $3::uuid
+			 RETURNING spend_limit IS NULL OR credits_spent <= spend_limit`,
+			amount, acct.OrgID, acct.UserID).Scan(&allowed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotOrgMember
+		}
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrSpendLimitReached
+		}
+
+		res, err := tx.Exec(ctx,
+			"UPDATE organizations SET credit_balance = credit_balance - $1 WHERE id = $2::uuid AND credit_balance >= $1",
+			amount, acct.OrgID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrInsufficientCredits
+		}
+		return writeLedger(ctx, tx, acct, -amount, reason)
+	})
+}
+
+// GrantCredits adds amount credits to acct. Refunds of org debits also
+// give the member's spending allowance back.
+func GrantCredits(ctx context.Context, db DB, acct Account, amount int, reason string) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if acct.OrgID == "" {
+			if _, err := tx.Exec(ctx,
+				"UPDATE users SET credit_balance = credit_balance + $1 WHERE id = $2::uuid",
+				amount, acct.UserID); err != nil {
+				return err
+			}
+			return writeLedger(ctx, tx, acct, amount, reason)
+		}
+
+		if _, err := tx.Exec(ctx,
+			"UPDATE organizations SET credit_balance = credit_balance + $1 WHERE id = $2::uuid",
+			amount, acct.OrgID); err != nil {
+			return err
+		}
+		if acct.UserID != "" && reason == ReasonRefund {
+			if _, err := tx.Exec(ctx,
+				"UPDATE organization_members SET credits_spent = GREATEST(credits_spent - $1, 0) WHERE org_id = $2::uuid AND user_id = $3::uuid",
+				amount, acct.OrgID, acct.UserID); err != nil {
+				return err
+			}
+		}
+		return writeLedger(ctx, tx, acct, amount, reason)
+	})
+}
+
+// Ledger reasons.
+const (
+	ReasonGenerate    = "generate"
+	ReasonEditSection = "edit_section"
+	ReasonRefund      = "refund"
+	ReasonTransfer    = "transfer"
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+func writeLedger(ctx context.Context, tx pgx.Tx, acct Account, delta int, reason string) error {
+	_, err := tx.Exec(ctx,
+		"INSERT INTO credit_ledger (user_id, org_id, delta, reason) VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::uuid, $3, $4)",
+		acct.UserID, acct.OrgID, delta, reason)
+	return err
+}
@@ -0,0 +1,41 @@
+package logic
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"baliance.com/gooxml/document"
+)
+
+func GenerateDOCX(userID string, content string) ([]byte, string, error) {
+	doc := document.New()
+	replacer := strings.NewReplacer("**", "", "__", "")
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		p := doc.AddParagraph()
+		switch {
+		case strings.HasPrefix(trimmed, "## "):
+			p.SetStyle("Heading2")
+			trimmed = trimmed[3:]
+		case strings.HasPrefix(trimmed, "# "):
+			p.SetStyle("Heading1")
+			trimmed = trimmed[2:]
+		case strings.HasPrefix(trimmed, "* "), strings.HasPrefix(trimmed, "- "):
+			trimmed = "• " + trimmed[2:]
+		}
+		p.AddRun().AddText(replacer.Replace(trimmed))
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Save(&buf); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), fmt.Sprintf("lesson_%s_%d.docx", userID, time.Now().Unix()), nil
+}
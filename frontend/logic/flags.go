@@ -0,0 +1,66 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrFlagNotFound = errors.New("flagged request not found")
+
+// FlaggedRequest is a request the input screening stage found problems
+// with. Findings and Input are stored as given.
+type FlaggedRequest struct {
+	ID         string          `json:"id"`
+	UserID     string          `json:"userId"`
+	Kind       string          `json:"kind"`   // generate or edit_section
+	Action     string          `json:"action"` // rejected, sanitized or flagged
+	Findings   json.RawMessage `json:"findings"`
+	Input      json.RawMessage `json:"input"`
+	ReviewedAt *time.Time      `json:"reviewedAt"`
+	ReviewedBy *string         `json:"reviewedBy"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// RecordFlaggedRequest stores f for review.
+func RecordFlaggedRequest(ctx context.Context, db DB, f FlaggedRequest) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO flagged_requests (user_id, kind, action, findings, input)
+		 VALUES ($1::uuid, $2, $3, $4, $5)`,
+		f.UserID, f.Kind, f.Action, f.Findings, f.Input)
+	return err
+}
+
+// ListFlaggedRequests returns up to limit flagged requests, newest first,
+// only those not yet reviewed unless all is set.
+func ListFlaggedRequests(ctx context.Context, db DB, all bool, limit int) ([]FlaggedRequest, error) {
+	rows, err := db.Query(ctx,
+		`SELECT id, COALESCE(user_id::text, ''), kind, action, findings, input, reviewed_at, reviewed_by, created_at
+		 FROM flagged_requests WHERE $1 OR reviewed_at IS NULL
+		 ORDER BY created_at DESC LIMIT $2`, all, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (FlaggedRequest, error) {
+		var f FlaggedRequest
+		err := row.Scan(&f.ID, &f.UserID, &f.Kind, &f.Action, &f.Findings, &f.Input, &f.ReviewedAt, &f.ReviewedBy, &f.CreatedAt)
+		return f, err
+	})
+}
+
+// ReviewFlaggedRequest marks a flagged request as reviewed by reviewerID.
+func ReviewFlaggedRequest(ctx context.Context, db DB, flagID, reviewerID string) error {
+	tag, err := db.Exec(ctx,
+		"UPDATE flagged_requests SET reviewed_at = now(), reviewed_by = $2::uuid WHERE id = $1::uuid",
+		flagID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFlagNotFound
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrGenerationNotFound = errors.New("generation not found")
+
+// Generation is a stored lesson or presentation with the AI output it was
+// rendered from.
+type Generation struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"userId"`
+	OrgID     *string  `json:"orgId"`
+	Prompt    string   `json:"prompt"`
+	Mode      string   `json:"mode"`
+	Grade     string   `json:"grade"`
+	Duration  string   `json:"duration"`
+	FilePath  string   `json:"filePath"`
+	Content   string   `json:"content"`
+	Structure Document `json:"structure"`
+	Version   int      `json:"version"`
+	// CopiedFrom is the generation this one was copied from via a share.
+	CopiedFrom *string `json:"copiedFrom,omitempty"`
+	// Usage, TemplateVersion and Moderation describe the AI calls behind
+	// the content being saved. They are written by SaveGeneration and
+	// SaveVersion but not loaded back.
+	Usage           *Usage             `json:"-"`
+	TemplateVersion string             `json:"-"`
+	Moderation      *safety.Moderation `json:"-"`
+	CreatedAt       time.Time          `json:"createdAt"`
+}
+
+// SaveGeneration inserts g as version 1, filling in its ID and creation
+// time.
+func SaveGeneration(ctx context.Context, db DB, g *Generation) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO generations (user_id, org_id, prompt, mode, grade, duration, file_path, raw_content, structure, copied_from, status, current_version,
+			                          template_version, moderation, provider, model, input_tokens, output_tokens, latency_ms, cost_usd)
+			 VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6, $7, $8, $9, $10::uuid, 'completed', 1, NULLIF($11, ''), $12, $13, $14, $15, $16, $17, $18)
+			 RETURNING id, created_at`,
+			append([]any{g.UserID, g.OrgID, g.Prompt, g.Mode, g.Grade, g.Duration, g.FilePath, g.Content, g.Structure, g.CopiedFrom, g.TemplateVersion, g.Moderation},
+				usageColumns(g.Usage)...)...,
+		).Scan(&g.ID, &g.CreatedAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			`INSERT INTO generation_versions (generation_id, version, content, structure, file_path, note, moderation)
+			 VALUES ($1::uuid, 1, $2, $3, $4, 'initial', $5)`,
+			g.ID, g.Content, g.Structure, g.FilePath, g.Moderation)
+		return err
+	})
+}
+
+// GetGeneration loads a generation owned by userID. Rows created before
+// content was persisted come back with an empty Content.
+func GetGeneration(ctx context.Context, db DB, generationID, userID string) (Generation, error) {
+	var g Generation
+	var content, filePath *string
+	var structure *Document
+	err := db.QueryRow(ctx,
+		`SELECT id, user_id, org_id, prompt, COALESCE(mode, ''), COALESCE(grade, ''), COALESCE(duration, ''),
+		        file_path, raw_content, structure, COALESCE(current_version, 1), created_at
+		 FROM generations WHERE id = $1::uuid AND user_id = $2::uuid`,
+		generationID, userID).Scan(&g.ID, &g.UserID, &g.OrgID, &g.Prompt, &g.Mode, &g.Grade, &g.Duration,
+		&filePath, &content, &structure, &g.Version, &g.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return g, ErrGenerationNotFound
+	}
+	if content != nil {
+		g.Content = *content
+	}
+	if filePath != nil {
+		g.FilePath = *filePath
+	}
+	if structure != nil {
+		g.Structure = *structure
+	}
+	return g, err
+}
+
+// GenerationFile returns the storage key of a generation owned by userID.
+func GenerationFile(ctx context.Context, db DB, generationID, userID string) (string, error) {
+	var path *string
+	err := db.QueryRow(ctx,
+		"SELECT file_path FROM generations WHERE id = $1::uuid AND user_id = $2::uuid",
+		generationID, userID).Scan(&path)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrGenerationNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if path == nil || *path == "" {
+		return "", ErrGenerationNotFound
+	}
+	return *path, nil
+}
+
+// legacyObjectPath is where Supabase served objects from public buckets,
+// which is how file_path was stored before files became private.
+const legacyObjectPath = "/storage/v1/object/public/"
+
+// StorageKey turns a stored file_path into a storage key. Legacy public
+// URLs are reduced to the key inside their bucket; ok is false for any
+// other absolute URL, which can't be signed.
+func StorageKey(path string) (key string, ok bool) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return path, true
+	}
+	u, err := url.Parse(path)
+	if err != nil || !strings.HasPrefix(u.Path, legacyObjectPath) {
+		return "", false
+	}
+	_, key, ok = strings.Cut(strings.TrimPrefix(u.Path, legacyObjectPath), "/")
+	return key, ok && key != ""
+}
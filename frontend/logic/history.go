@@ -0,0 +1,265 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrFolderExists   = errors.New("folder already exists")
+	ErrInvalidTags    = errors.New("invalid tags")
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 32
+	MaxPageSize  = 100
+)
+
+// HistoryFilter selects generations for GET /api/generations. Zero values
+// mean "don't filter".
+type HistoryFilter struct {
+	Query    string
+	Mode     string
+	Grade    string
+	Tag      string
+	FolderID string
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+// Normalized returns f with paging clamped to valid values.
+func (f HistoryFilter) Normalized() HistoryFilter {
+	if f.PageSize <= 0 || f.PageSize > MaxPageSize {
+		f.PageSize = 20
+	}
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	return f
+}
+
+// HistoryItem is a generation as listed in history, without its content.
+type HistoryItem struct {
+	ID        string    `json:"id"`
+	Prompt    string    `json:"prompt"`
+	Mode      string    `json:"mode"`
+	Grade     string    `json:"grade"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	FolderID  *string   `json:"folderId"`
+	Snippet   string    `json:"snippet,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Folder struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SearchHistory returns one page of userID's generations matching f, plus
+// the total number of matches.
+func SearchHistory(ctx context.Context, db DB, userID string, f HistoryFilter) ([]HistoryItem, int, error) {
+	where := []string{"user_id = $1::uuid"}
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	snippet := "''"
+	if q := strings.TrimSpace(f.Query); q != "" {
+		p := arg(q)
+		where = append(where, "search @@ websearch_to_tsquery('english', "+p+")")
+		snippet = "ts_headline('english', COALESCE(raw_content, prompt), websearch_to_tsquery('english', " + p + "), 'MaxFragments=1, MaxWords=20, MinWords=8')"
+	}
+	if f.Mode != "" {
+		where = append(where, "mode = "+arg(f.Mode))
+	}
+	if f.Grade != "" {
+		where = append(where, "grade = "+arg(f.Grade))
+	}
+	if f.Tag != "" {
+		where = append(where, arg(strings.ToLower(f.Tag))+" = ANY(tags)")
+	}
+	if f.FolderID != "" {
+		where = append(where, "folder_id = "+arg(f.FolderID)+"::uuid")
+	}
+	if !f.From.IsZero() {
+		where = append(where, "created_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "created_at < "+arg(f.To))
+	}
+	cond := strings.Join(where, " AND ")
+
+	var total int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM generations WHERE "+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	f = f.Normalized()
+	limit, offset := arg(f.PageSize), arg((f.Page-1)*f.PageSize)
+	rows, err := db.Query(ctx,
+		`SELECT id, prompt, COALESCE(mode, ''), COALESCE(grade, ''), COALESCE(structure->>'title', ''),
+		        tags, folder_id, `+snippet+`, created_at
+		 FROM generations WHERE `+cond+`
+		 ORDER BY created_at DESC, id LIMIT `+limit+` OFFSET `+offset, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (HistoryItem, error) {
+		var it HistoryItem
+		err := row.Scan(&it.ID, &it.Prompt, &it.Mode, &it.Grade, &it.Title, &it.Tags, &it.FolderID, &it.Snippet, &it.CreatedAt)
+		return it, err
+	})
+	return items, total, err
+}
+
+// NormalizeTags lowercases, trims and de-duplicates tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if len(t) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	if len(out) > maxTags {
+		return nil, ErrInvalidTags
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func SetTags(ctx context.Context, db DB, generationID, userID string, tags []string) error {
+	res, err := db.Exec(ctx,
+		"UPDATE generations SET tags = $3 WHERE id = $1::uuid AND user_id = $2::uuid",
+		generationID, userID, tags)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrGenerationNotFound
+	}
+	return nil
+}
+
+// MoveToFolder files a generation under folderID, or takes it out of any
+// folder when folderID is empty.
+func MoveToFolder(ctx context.Context, db DB, generationID, userID, folderID string) error {
+	if folderID != "" {
+		var exists bool
+		if err := db.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM folders WHERE id = $1::uuid AND user_id = $2::uuid)",
+			folderID, userID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrFolderNotFound
+		}
+	}
+	res, err := db.Exec(ctx,
+		"UPDATE generations SET folder_id = NULLIF($3, '')::uuid WHERE id = $1::uuid AND user_id = $2::uuid",
+		generationID, userID, folderID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrGenerationNotFound
+	}
+	return nil
+}
+
+// TagCounts returns every tag userID has used with how many generations
+// carry it.
+func TagCounts(ctx context.Context, db DB, userID string) (map[string]int, error) {
+	rows, err := db.Query(ctx,
+		"SELECT tag, count(*) FROM generations, unnest(tags) AS tag WHERE user_id = $1::uuid GROUP BY tag",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var tag string
+		var n int
+		if err := rows.Scan(&tag, &n); err != nil {
+			return nil, err
+		}
+		counts[tag] = n
+	}
+	return counts, rows.Err()
+}
+
+func ListFolders(ctx context.Context, db DB, userID string) ([]Folder, error) {
+	rows, err := db.Query(ctx,
+		`SELECT f.id, f.name, count(g.id), f.created_at FROM folders f
+		 LEFT JOIN generations g ON g.folder_id = f.id
+		 WHERE f.user_id = $1::uuid GROUP BY f.id ORDER BY f.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Folder, error) {
+		var f Folder
+		err := row.Scan(&f.ID, &f.Name, &f.Count, &f.CreatedAt)
+		return f, err
+	})
+}
+
+func CreateFolder(ctx context.Context, db DB, userID, name string) (Folder, error) {
+	f := Folder{Name: name}
+	err := db.QueryRow(ctx,
+		"INSERT INTO folders (user_id, name) VALUES ($1::uuid, $2) RETURNING id, created_at",
+		userID, name).Scan(&f.ID, &f.CreatedAt)
+	if isUniqueViolation(err) {
+		return f, ErrFolderExists
+	}
+	return f, err
+}
+
+func RenameFolder(ctx context.Context, db DB, folderID, userID, name string) error {
+	res, err := db.Exec(ctx,
+		"UPDATE folders SET name = $3 WHERE id = $1::uuid AND user_id = $2::uuid",
+		folderID, userID, name)
+	if isUniqueViolation(err) {
+		return ErrFolderExists
+	}
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// DeleteFolder removes a folder; its generations stay in the library.
+func DeleteFolder(ctx context.Context, db DB, folderID, userID string) error {
+	res, err := db.Exec(ctx, "DELETE FROM folders WHERE id = $1::uuid AND user_id = $2::uuid", folderID, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package logic
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// User is the authenticated caller, taken from verified token claims or
+// from the API key used on the request.
+type User struct {
+	ID    string
+	Email string
+	Role  string
+
+	APIKeyID string
+	Scopes   []string
+}
+
+type userKey struct{}
+
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFrom returns the user stored by the auth middleware.
+func UserFrom(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// TokenVerifier checks Supabase access tokens locally. HS256 tokens are
+// verified with Secret; RS256/ES256 tokens with keys fetched from JWKSURL,
+// cached for CacheTTL and refetched when an unknown key ID appears.
+type TokenVerifier struct {
+	Secret   []byte
+	JWKSURL  string
+	Issuer   string
+	Audience string
+	CacheTTL time.Duration
+	Leeway   time.Duration
+	Client   *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewSupabaseVerifier builds a verifier for the project at supabaseURL.
+func NewSupabaseVerifier(supabaseURL, jwtSecret string) *TokenVerifier {
+	base := strings.TrimSuffix(supabaseURL, "/") + "/auth/v1"
+	v := &TokenVerifier{
+		JWKSURL:  base + "/.well-known/jwks.json",
+		Issuer:   base,
+		Audience: "authenticated",
+		CacheTTL: 10 * time.Minute,
+		Leeway:   30 * time.Second,
+		Client:   &http.Client{Timeout: 5 * time.Second},
+	}
+	if jwtSecret != "" {
+		v.Secret = []byte(jwtSecret)
+	}
+	return v
+}
+
+type jwtClaims struct {
+	Sub   string          `json:"sub"`
+	Email string          `json:"email"`
+	Role  string          `json:"role"`
+	Iss   string          `json:"iss"`
+	Aud   json.RawMessage `json:"aud"`
+	Exp   int64           `json:"exp"`
+	Nbf   int64           `json:"nbf"`
+}
+
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return User{}, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return User{}, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if len(v.Secret) == 0 {
+			return User{}, fmt.Errorf("%w: HS256 not configured", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return User{}, ErrInvalidToken
+		}
+	case "RS256", "ES256":
+		key, err := v.key(ctx, header.Kid)
+		if err != nil {
+			return User{}, err
+		}
+		if !verifySignature(header.Alg, key, signed, sig) {
+			return User{}, ErrInvalidToken
+		}
+	default:
+		return User{}, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+
+	var c jwtClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return User{}, ErrInvalidToken
+	}
+	now := time.Now()
+	if c.Exp == 0 || now.After(time.Unix(c.Exp, 0).Add(v.Leeway)) {
+		return User{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if c.Nbf != 0 && now.Add(v.Leeway).Before(time.Unix(c.Nbf, 0)) {
+		return User{}, fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+	}
+	if v.Issuer != "" && c.Iss != v.Issuer {
+		return User{}, fmt.Errorf("%w: issuer", ErrInvalidToken)
+	}
+	if v.Audience != "" && !audienceContains(c.Aud, v.Audience) {
+		return User{}, fmt.Errorf("%w: audience", ErrInvalidToken)
+	}
+	if c.Sub == "" {
+		return User{}, ErrInvalidToken
+	}
+	return User{ID: c.Sub, Email: c.Email, Role: c.Role}, nil
+}
+
+// key returns the public key for kid, refreshing the JWKS when the cache
+// is stale or the key is unknown (i.e. the signing key was rotated).
+func (v *TokenVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.CacheTTL
+	recent := time.Since(v.fetchedAt) < 30*time.Second
+	v.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+	// Don't let a stream of bogus kids hammer the JWKS endpoint.
+	if !ok && recent {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidToken)
+	}
+	if err := v.refresh(ctx); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key", ErrInvalidToken)
+}
+
+func (v *TokenVerifier) refresh(ctx context.Context) error {
+	if v.JWKSURL == "" {
+		return errors.New("jwks url not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", v.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks fetch: status %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	}
+	return false
+}
+
+func audienceContains(raw json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
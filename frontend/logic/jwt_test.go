@@ -0,0 +1,287 @@
+package logic
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://proj.supabase.co/auth/v1"
+	testAudience = "authenticated"
+	testSecret   = "super-secret-jwt-token-with-at-least-32-characters"
+)
+
+var b64 = base64.RawURLEncoding
+
+type jwtKeys struct {
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	rotated *rsa.PrivateKey
+}
+
+func newJWTKeys(t *testing.T) jwtKeys {
+	t.Helper()
+	r, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwtKeys{rsa: r, ec: e, rotated: r2}
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) map[string]string {
+	return map[string]string{"kid": kid, "kty": "RSA", "n": b64.EncodeToString(k.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(k.E)).Bytes())}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) map[string]string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	k.X.FillBytes(x)
+	k.Y.FillBytes(y)
+	return map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": b64.EncodeToString(x), "y": b64.EncodeToString(y)}
+}
+
+// signJWT builds a token. key is a []byte HMAC secret, an RSA or EC
+// private key, or nil for an unsigned token.
+func signJWT(t *testing.T, header map[string]string, claims map[string]any, key any) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(h) + "." + b64.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case nil:
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "8f14e45f-ceea-467a-9af0-2c8b5e2f3b6a",
+		"email": "t@example.com",
+		"role":  "authenticated",
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func with(c map[string]any, k string, v any) map[string]any {
+	out := map[string]any{}
+	for key, val := range c {
+		out[key] = val
+	}
+	if v == nil {
+		delete(out, k)
+	} else {
+		out[k] = v
+	}
+	return out
+}
+
+// jwksServer serves the keys in *set and counts fetches.
+func jwksServer(t *testing.T, set *atomic.Value, fetches *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": set.Load()})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestVerifier(jwksURL string, secret []byte) *TokenVerifier {
+	return &TokenVerifier{
+		Secret:   secret,
+		JWKSURL:  jwksURL,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		CacheTTL: 10 * time.Minute,
+		Leeway:   30 * time.Second,
+		Client:   http.DefaultClient,
+	}
+}
+
+func TestTokenVerifier(t *testing.T) {
+	keys := newJWTKeys(t)
+	var set atomic.Value
+	set.Store([]map[string]string{rsaJWK("rsa1", &keys.rsa.PublicKey), ecJWK("ec1", &keys.ec.PublicKey)})
+	var fetches atomic.Int32
+	srv := jwksServer(t, &set, &fetches)
+
+	rsaPub, _ := x509.MarshalPKIXPublicKey(&keys.rsa.PublicKey)
+	hs := map[string]string{"alg": "HS256", "typ": "JWT"}
+	rs := map[string]string{"alg": "RS256", "typ": "JWT", "kid": "rsa1"}
+	es := map[string]string{"alg": "ES256", "typ": "JWT", "kid": "ec1"}
+	now := time.Now()
+
+	cases := []struct {
+		name   string
+		token  string
+		secret []byte
+		ok     bool
+	}{
+		{"HS256 valid", signJWT(t, hs, validClaims(), []byte(testSecret)), []byte(testSecret), true},
+		{"RS256 valid", signJWT(t, rs, validClaims(), keys.rsa), nil, true},
+		{"ES256 valid", signJWT(t, es, validClaims(), keys.ec), nil, true},
+		{"audience list", signJWT(t, rs, with(validClaims(), "aud", []string{"other", testAudience}), keys.rsa), nil, true},
+
+		{"HS256 wrong secret", signJWT(t, hs, validClaims(), []byte("another-secret")), []byte(testSecret), false},
+		{"HS256 without secret configured", signJWT(t, hs, validClaims(), []byte(testSecret)), nil, false},
+		{"RS256 signed by another key", signJWT(t, rs, validClaims(), keys.rotated), nil, false},
+		{"ES256 signed by another key", signJWT(t, es, validClaims(), mustEC(t)), nil, false},
+		{"tampered claims", tamper(t, signJWT(t, rs, validClaims(), keys.rsa)), nil, false},
+
+		{"expired", signJWT(t, rs, with(validClaims(), "exp", now.Add(-time.Minute).Unix()), keys.rsa), nil, false},
+		{"expired within leeway", signJWT(t, rs, with(validClaims(), "exp", now.Add(-10*time.Second).Unix()), keys.rsa), nil, true},
+		{"no exp", signJWT(t, rs, with(validClaims(), "exp", nil), keys.rsa), nil, false},
+		{"not yet valid", signJWT(t, rs, with(validClaims(), "nbf", now.Add(time.Minute).Unix()), keys.rsa), nil, false},
+		{"nbf within leeway", signJWT(t, rs, with(validClaims(), "nbf", now.Add(10*time.Second).Unix()), keys.rsa), nil, true},
+		{"wrong issuer", signJWT(t, rs, with(validClaims(), "iss", "https://evil.example/auth/v1"), keys.rsa), nil, false},
+		{"wrong audience", signJWT(t, rs, with(validClaims(), "aud", "anon"), keys.rsa), nil, false},
+		{"missing audience", signJWT(t, rs, with(validClaims(), "aud", nil), keys.rsa), nil, false},
+		{"missing sub", signJWT(t, rs, with(validClaims(), "sub", nil), keys.rsa), nil, false},
+
+		{"unknown kid", signJWT(t, map[string]string{"alg": "RS256", "kid": "nope"}, validClaims(), keys.rsa), nil, false},
+		{"alg none", signJWT(t, map[string]string{"alg": "none"}, validClaims(), nil), []byte(testSecret), false},
+		{"alg None", signJWT(t, map[string]string{"alg": "None"}, validClaims(), nil), []byte(testSecret), false},
+		{"alg HS512", signJWT(t, map[string]string{"alg": "HS512"}, validClaims(), []byte(testSecret)), []byte(testSecret), false},
+		// HS/RS confusion: an HMAC signed with the RSA public key, sent
+		// with an RS256 header or with HS256 while no secret is set.
+		{"HS signature under RS256 header", signJWT(t, rs, validClaims(), rsaPub), nil, false},
+		{"HS256 keyed with RSA public key", signJWT(t, map[string]string{"alg": "HS256", "kid": "rsa1"}, validClaims(), rsaPub), nil, false},
+		{"RS256 header naming an EC key", signJWT(t, map[string]string{"alg": "RS256", "kid": "ec1"}, validClaims(), keys.ec), nil, false},
+		{"ES256 header naming an RSA key", signJWT(t, map[string]string{"alg": "ES256", "kid": "rsa1"}, validClaims(), keys.rsa), nil, false},
+
+		{"two segments", "a.b", nil, false},
+		{"garbage", "not-a-token", nil, false},
+	}
+	for _, c := range cases {
+		v := newTestVerifier(srv.URL, c.secret)
+		u, err := v.Verify(context.Background(), c.token)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok=%v", c.name, err, c.ok)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", c.name, err)
+		}
+		if c.ok && (u.ID != "8f14e45f-ceea-467a-9af0-2c8b5e2f3b6a" || u.Email != "t@example.com") {
+			t.Errorf("%s: user = %+v", c.name, u)
+		}
+	}
+}
+
+func TestTokenVerifierKeyRotation(t *testing.T) {
+	keys := newJWTKeys(t)
+	var set atomic.Value
+	set.Store([]map[string]string{rsaJWK("old", &keys.rsa.PublicKey)})
+	var fetches atomic.Int32
+	srv := jwksServer(t, &set, &fetches)
+	v := newTestVerifier(srv.URL, nil)
+	ctx := context.Background()
+
+	oldToken := signJWT(t, map[string]string{"alg": "RS256", "kid": "old"}, validClaims(), keys.rsa)
+	newToken := signJWT(t, map[string]string{"alg": "RS256", "kid": "new"}, validClaims(), keys.rotated)
+
+	if _, err := v.Verify(ctx, oldToken); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+	if _, err := v.Verify(ctx, oldToken); err != nil || fetches.Load() != 1 {
+		t.Fatalf("cached key: err=%v fetches=%d, want one fetch", err, fetches.Load())
+	}
+
+	set.Store([]map[string]string{rsaJWK("old", &keys.rsa.PublicKey), rsaJWK("new", &keys.rotated.PublicKey)})
+	// Right after a fetch unknown kids are refused without refetching.
+	if _, err := v.Verify(ctx, newToken); err == nil || fetches.Load() != 1 {
+		t.Fatalf("new key right after fetch: err=%v fetches=%d", err, fetches.Load())
+	}
+	v.fetchedAt = time.Now().Add(-time.Minute)
+	if _, err := v.Verify(ctx, newToken); err != nil || fetches.Load() != 2 {
+		t.Fatalf("rotated key: err=%v fetches=%d, want a refetch", err, fetches.Load())
+	}
+
+	// Once the old key is dropped and the cache expires, its tokens fail.
+	set.Store([]map[string]string{rsaJWK("new", &keys.rotated.PublicKey)})
+	v.fetchedAt = time.Now().Add(-time.Hour)
+	if _, err := v.Verify(ctx, oldToken); err == nil {
+		t.Fatal("token signed with a retired key was accepted")
+	}
+	if _, err := v.Verify(ctx, newToken); err != nil {
+		t.Fatalf("new key after retiring the old one: %v", err)
+	}
+}
+
+func TestTokenVerifierKeepsCachedKeyWhenJWKSDown(t *testing.T) {
+	keys := newJWTKeys(t)
+	var set atomic.Value
+	set.Store([]map[string]string{rsaJWK("rsa1", &keys.rsa.PublicKey)})
+	var fetches atomic.Int32
+	srv := jwksServer(t, &set, &fetches)
+	v := newTestVerifier(srv.URL, nil)
+	token := signJWT(t, map[string]string{"alg": "RS256", "kid": "rsa1"}, validClaims(), keys.rsa)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	v.fetchedAt = time.Now().Add(-time.Hour)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("stale cached key with JWKS down: %v", err)
+	}
+}
+
+func mustEC(t *testing.T) *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// tamper swaps the claims of token for ones naming another user, keeping
+// the original signature.
+func tamper(t *testing.T, token string) string {
+	c, _ := json.Marshal(with(validClaims(), "sub", "00000000-0000-0000-0000-000000000000"))
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + b64.EncodeToString(c) + "." + parts[2]
+}
@@ -0,0 +1,195 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Organization roles, in decreasing order of privilege.
+const (
+	RoleOwner   = "owner"
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrLastOwner     = errors.New("organization must keep at least one owner")
+	ErrAlreadyMember = errors.New("user is already a member")
+)
+
+type Organization struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	CreditBalance int       `json:"creditBalance"`
+	Role          string    `json:"role,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type OrgMember struct {
+	UserID       string `json:"userId"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	SpendLimit   *int   `json:"spendLimit"`
+	CreditsSpent int    `json:"creditsSpent"`
+}
+
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleTeacher
+}
+
+// CanManage reports whether role may add, edit and remove members and move
+// credits into the pool.
+func CanManage(role string) bool {
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// CreateOrg creates an organization with ownerID as its first owner.
+func CreateOrg(ctx context.Context, db DB, ownerID, name string) (Organization, error) {
+	org := Organization{Name: name, Role: RoleOwner}
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx,
+			"INSERT INTO organizations (name) VALUES ($1) RETURNING id, credit_balance, created_at",
+			name).Scan(&org.ID, &org.CreditBalance, &org.CreatedAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO organization_members (org_id, user_id, role) VALUES ($1::uuid, $2::uuid, $3)",
+			org.ID, ownerID, RoleOwner)
+		return err
+	})
+	return org, err
+}
+
+// ListOrgs returns every organization userID belongs to, with their role.
+func ListOrgs(ctx context.Context, db DB, userID string) ([]Organization, error) {
+	rows, err := db.Query(ctx,
+		`SELECT o.id, o.name, o.credit_balance, m.role, o.created_at
+		 FROM organizations o JOIN organization_members m ON m.org_id = o.id
+		 WHERE m.user_id = $1::uuid ORDER BY o.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Organization, error) {
+		var o Organization
+		err := row.Scan(&o.ID, &o.Name, &o.CreditBalance, &o.Role, &o.CreatedAt)
+		return o, err
+	})
+}
+
+// MemberRole returns userID's role in orgID, or ErrNotOrgMember.
+func MemberRole(ctx context.Context, db DB, orgID, userID string) (string, error) {
+	var role string
+	err := db.QueryRow(ctx,
+		"SELECT role FROM organization_members WHERE org_id = $1::uuid AND user_id = $2::uuid",
+		orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotOrgMember
+	}
+	return role, err
+}
+
+func GetOrg(ctx context.Context, db DB, orgID string) (Organization, []OrgMember, error) {
+	var org Organization
+	if err := db.QueryRow(ctx,
+		"SELECT id, name, credit_balance, created_at FROM organizations WHERE id = $1::uuid",
+		orgID).Scan(&org.ID, &org.Name, &org.CreditBalance, &org.CreatedAt); err != nil {
+		return org, nil, err
+	}
+	rows, err := db.Query(ctx,
+		`SELECT m.user_id, u.email, m.role, m.spend_limit, m.credits_spent
+		 FROM organization_members m JOIN users u ON u.id = m.user_id
+		 WHERE m.org_id = $1::uuid ORDER BY u.email`, orgID)
+	if err != nil {
+		return org, nil, err
+	}
+	members, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (OrgMember, error) {
+		var m OrgMember
+		err := row.Scan(&m.UserID, &m.Email, &m.Role, &m.SpendLimit, &m.CreditsSpent)
+		return m, err
+	})
+	return org, members, err
+}
+
+// AddMember adds the user registered under email to orgID. Existing
+// members are left as they are (ErrAlreadyMember); their role changes go
+// through UpdateMember and its owner checks.
+func AddMember(ctx context.Context, db DB, orgID, email, role string, spendLimit *int) (OrgMember, error) {
+	m := OrgMember{Email: email, Role: role, SpendLimit: spendLimit}
+	err := db.QueryRow(ctx, "SELECT id FROM users WHERE lower(email) = lower($1)", email).Scan(&m.UserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return m, ErrUserNotFound
+	}
+	if err != nil {
+		return m, err
+	}
+	res, err := db.Exec(ctx,
+		`INSERT INTO organization_members (org_id, user_id, role, spend_limit) VALUES ($1::uuid, $2::uuid, $3, $4)
+		 ON CONFLICT (org_id, user_id) DO NOTHING`,
+		orgID, m.UserID, role, spendLimit)
+	if err == nil && res.RowsAffected() == 0 {
+		return m, ErrAlreadyMember
+	}
+	return m, err
+}
+
+// UpdateMember changes a member's role and spending limit. resetSpent
+// zeroes the running total, e.g. at the start of a new term.
+func UpdateMember(ctx context.Context, db DB, orgID, userID, role string, spendLimit *int, resetSpent bool) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx,
+			`UPDATE organization_members SET role = $3, spend_limit = $4,
+			 credits_spent = CASE WHEN $5 THEN 0 ELSE credits_spent END
+			 WHERE org_id = $1::uuid AND user_id = $2::uuid`,
+			orgID, userID, role, spendLimit, resetSpent)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrNotOrgMember
+		}
+		return ensureOwner(ctx, tx, orgID)
+	})
+}
+
+func RemoveMember(ctx context.Context, db DB, orgID, userID string) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx,
+			"DELETE FROM organization_members WHERE org_id = $1::uuid AND user_id = $2::uuid",
+			orgID, userID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrNotOrgMember
+		}
+		return ensureOwner(ctx, tx, orgID)
+	})
+}
+
+// TransferToOrg moves credits from a member's personal balance into the
+// organization pool, so credits bought once can be shared by the team.
+func TransferToOrg(ctx context.Context, db DB, orgID, userID string, amount int) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if err := DebitCredits(ctx, tx, Account{UserID: userID}, amount, ReasonTransfer); err != nil {
+			return err
+		}
+		return GrantCredits(ctx, tx, Account{UserID: userID, OrgID: orgID}, amount, ReasonTransfer)
+	})
+}
+
+func ensureOwner(ctx context.Context, tx pgx.Tx, orgID string) error {
+	var owners int
+	if err := tx.QueryRow(ctx,
+		"SELECT count(*) FROM organization_members WHERE org_id = $1::uuid AND role = $2",
+		orgID, RoleOwner).Scan(&owners); err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
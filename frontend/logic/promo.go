@@ -0,0 +1,216 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReferralBonus is granted to both the referrer and the referred user when
+// the referred user completes their first purchase.
+const ReferralBonus = 5
+
+const (
+	ReasonPromo    = "promo"
+	ReasonReferral = "referral"
+	ReasonPurchase = "purchase"
+)
+
+var (
+	ErrInvalidCode     = errors.New("code not found")
+	ErrCodeExpired     = errors.New("code expired")
+	ErrCodeExhausted   = errors.New("code has no redemptions left")
+	ErrAlreadyRedeemed = errors.New("code already redeemed")
+	ErrSelfReferral    = errors.New("cannot use your own referral code")
+	ErrAlreadyReferred = errors.New("referral already set")
+)
+
+// RedeemPromo grants the credits behind code to userID once, returning the
+// number of credits granted.
+func RedeemPromo(ctx context.Context, db DB, userID, code string) (int, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var credits int
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		var maxRedemptions *int
+		var redemptions int
+		var expiresAt *time.Time
+		err := tx.QueryRow(ctx,
+			"SELECT credits, max_redemptions, redemptions, expires_at FROM promo_codes WHERE code = $1 FOR UPDATE",
+			code).Scan(&credits, &maxRedemptions, &redemptions, &expiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidCode
+		}
+		if err != nil {
+			return err
+		}
+		if expiresAt != nil && time.Now().After(*expiresAt) {
+			return ErrCodeExpired
+		}
+		if maxRedemptions != nil && redemptions >= *maxRedemptions {
+			return ErrCodeExhausted
+		}
+
+		res, err := tx.Exec(ctx,
+			"INSERT INTO promo_redemptions (code, user_id) VALUES ($1, $2::uuid) ON CONFLICT DO NOTHING",
+			code, userID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrAlreadyRedeemed
+		}
+		if _, err := tx.Exec(ctx, "UPDATE promo_codes SET redemptions = redemptions + 1 WHERE code = $1", code); err != nil {
+			return err
+		}
+		return GrantCredits(ctx, tx, Account{UserID: userID}, credits, ReasonPromo)
+	})
+	return credits, err
+}
+
+// ReferralCode returns userID's referral code, creating one on first use.
+func ReferralCode(ctx context.Context, db DB, userID string) (string, error) {
+	var code *string
+	if err := db.QueryRow(ctx, "SELECT referral_code FROM users WHERE id = $1::uuid", userID).Scan(&code); err != nil {
+		return "", err
+	}
+	if code != nil {
+		return *code, nil
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		c, err := randomCode(8)
+		if err != nil {
+			return "", err
+		}
+		var stored string
+		err = db.QueryRow(ctx,
+			`UPDATE users SET referral_code = COALESCE(referral_code, $2) WHERE id = $1::uuid RETURNING referral_code`,
+			userID, c).Scan(&stored)
+		if err == nil {
+			return stored, nil
+		}
+		if !isUniqueViolation(err) {
+			return "", err
+		}
+	}
+	return "", errors.New("could not allocate referral code")
+}
+
+// ClaimReferral records that userID was referred by the owner of code.
+// It only succeeds before the user's first completed purchase.
+func ClaimReferral(ctx context.Context, db DB, userID, code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		var referrerID string
+		err := tx.QueryRow(ctx, "SELECT id FROM users WHERE referral_code = $1", code).Scan(&referrerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidCode
+		}
+		if err != nil {
+			return err
+		}
+		if referrerID == userID {
+			return ErrSelfReferral
+		}
+		res, err := tx.Exec(ctx,
+			`UPDATE users SET referred_by = $2::uuid
+			 WHERE id = $1::uuid AND referred_by IS NULL
+			 AND NOT EXISTS (SELECT 1 FROM transactions WHERE user_id = $1::uuid AND status = 'completed')`,
+			userID, referrerID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrAlreadyReferred
+		}
+		return nil
+	})
+}
+
+// Purchase is a paid Stripe Checkout session.
+type Purchase struct {
+	SessionID   string
+	UserID      string
+	AmountCents int
+	Credits     int
+}
+
+// RecordPurchase stores a paid checkout session and completes it. Sessions
+// started from a Payment Link have no pending transaction row, so one is
+// created here first. Repeated calls for the same session do nothing. It
+// returns ErrUserNotFound if p.UserID has no account.
+func RecordPurchase(ctx context.Context, db DB, p Purchase) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO transactions (user_id, stripe_session_id, amount_cents, credits_added, status)
+			 VALUES ($1::uuid, $2, $3, $4, 'pending')
+			 ON CONFLICT (stripe_session_id) DO NOTHING`,
+			p.UserID, p.SessionID, p.AmountCents, p.Credits)
+		if isForeignKeyViolation(err) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return CompletePurchase(ctx, tx, p.SessionID)
+	})
+}
+
+// CompletePurchase marks a pending Stripe session as completed and credits
+// the buyer (or their organization). On the buyer's first completed
+// purchase both they and their referrer receive ReferralBonus credits.
+// It is idempotent so webhook retries are safe.
+func CompletePurchase(ctx context.Context, db DB, stripeSessionID string) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		var userID string
+		var orgID *string
+		var credits int
+		err := tx.QueryRow(ctx,
+			`UPDATE transactions SET status = 'completed'
+			 WHERE stripe_session_id = $1 AND status = 'pending'
+			 RETURNING user_id, org_id, credits_added`,
+			stripeSessionID).Scan(&userID, &orgID, &credits)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		acct := Account{UserID: userID}
+		if orgID != nil {
+			acct.OrgID = *orgID
+		}
+		if err := GrantCredits(ctx, tx, acct, credits, ReasonPurchase); err != nil {
+			return err
+		}
+
+		var referrerID *string
+		err = tx.QueryRow(ctx,
+			`UPDATE users SET referral_rewarded_at = CURRENT_TIMESTAMP
+			 WHERE id = $1::uuid AND referred_by IS NOT NULL AND referral_rewarded_at IS NULL
+			 RETURNING referred_by`,
+			userID).Scan(&referrerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := GrantCredits(ctx, tx, Account{UserID: userID}, ReferralBonus, ReasonReferral); err != nil {
+			return err
+		}
+		return GrantCredits(ctx, tx, Account{UserID: *referrerID}, ReferralBonus, ReasonReferral)
+	})
+}
+
+func randomCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:n], nil
+}
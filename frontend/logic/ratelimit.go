@@ -0,0 +1,147 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Bucket is a token bucket: Burst requests at once, refilled at Rate
+// tokens per second.
+type Bucket struct {
+	Burst float64
+	Rate  float64
+}
+
+// Default limits for /api/generate. The per-IP bucket is looser so several
+// teachers behind one school NAT don't starve each other.
+var (
+	UserGenerateBucket = Bucket{Burst: 10, Rate: 1.0 / 30}
+	IPGenerateBucket   = Bucket{Burst: 30, Rate: 10.0 / 60}
+)
+
+// MaxConcurrentGenerations caps in-flight generations per user.
+const MaxConcurrentGenerations = 2
+
+// staleSlotAfter reclaims slots left behind by instances that died
+// mid-request; it comfortably exceeds the function's max duration.
+const staleSlotAfter = 5 * time.Minute
+
+// LimitError is returned when a request must be rejected with 429.
+type LimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string { return e.Reason }
+
+// Limit is one bucket, stored under Key, that a request draws from.
+type Limit struct {
+	Key    string
+	Bucket Bucket
+}
+
+// pruneEvery and pruneIdle control clean-up of rate_limits: about one call
+// in pruneEvery deletes rows idle for longer than pruneIdle, by which time
+// every bucket has refilled and a missing row means the same thing.
+const (
+	pruneEvery = 100
+	pruneIdle  = time.Hour
+)
+
+// TakeTokens consumes one token from each limit, or from none of them if
+// any is empty, so a request refused by one bucket doesn't use up the
+// others. Bucket state lives in Postgres so the limits hold across
+// instances.
+func TakeTokens(ctx context.Context, db DB, limits ...Limit) error {
+	// Lock rows in a fixed order so concurrent callers can't deadlock.
+	limits = slices.Clone(limits)
+	slices.SortFunc(limits, func(a, b Limit) int { return strings.Compare(a.Key, b.Key) })
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		tokens := make([]float64, len(limits))
+		var refused *LimitError
+		for i, l := range limits {
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, clock_timestamp()) ON CONFLICT (key) DO NOTHING",
+				l.Key, l.Bucket.Burst); err != nil {
+				return err
+			}
+			var elapsed float64
+			if err := tx.QueryRow(ctx,
+				"SELECT tokens, EXTRACT(EPOCH FROM clock_timestamp() - updated_at)::float8 FROM rate_limits WHERE key = $1 FOR UPDATE",
+				l.Key).Scan(&tokens[i], &elapsed); err != nil {
+				return err
+			}
+			tokens[i] = math.Min(l.Bucket.Burst, tokens[i]+math.Max(elapsed, 0)*l.Bucket.Rate)
+			if tokens[i] < 1 {
+				wait := time.Duration(math.Ceil((1-tokens[i])/l.Bucket.Rate)) * time.Second
+				if refused == nil || wait > refused.RetryAfter {
+					refused = &LimitError{Reason: "rate limit exceeded", RetryAfter: wait}
+				}
+			}
+		}
+		if refused != nil {
+			return refused
+		}
+		for i, l := range limits {
+			if _, err := tx.Exec(ctx,
+				"UPDATE rate_limits SET tokens = $2, updated_at = clock_timestamp() WHERE key = $1",
+				l.Key, tokens[i]-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if rand.IntN(pruneEvery) == 0 {
+		db.Exec(ctx, "DELETE FROM rate_limits WHERE updated_at < clock_timestamp() - make_interval(secs => $1)", pruneIdle.Seconds())
+	}
+	return err
+}
+
+// AcquireGenerationSlot reserves one of the user's concurrent generation
+// slots. The returned release func must be called when the work is done.
+func AcquireGenerationSlot(ctx context.Context, db DB, userID string) (release func(), err error) {
+	var slotID string
+	err = pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		// Serialise acquisitions per user; the lock is released on commit.
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('generation_slots:' || $1))", userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx,
+			"DELETE FROM generation_slots WHERE user_id = $1::uuid AND started_at < clock_timestamp() - make_interval(secs => $2)",
+			userID, staleSlotAfter.Seconds()); err != nil {
+			return err
+		}
+		var inFlight int
+		if err := tx.QueryRow(ctx,
+			"SELECT count(*) FROM generation_slots WHERE user_id = $1::uuid", userID).Scan(&inFlight); err != nil {
+			return err
+		}
+		if inFlight >= MaxConcurrentGenerations {
+			return &LimitError{Reason: "too many generations in progress", RetryAfter: 10 * time.Second}
+		}
+		return tx.QueryRow(ctx,
+			"INSERT INTO generation_slots (user_id) VALUES ($1::uuid) RETURNING id", userID).Scan(&slotID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		// The request context may already be cancelled when we get here.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		db.Exec(ctx, "DELETE FROM generation_slots WHERE id = $1::uuid", slotID)
+	}, nil
+}
+
+func IsLimitError(err error) (*LimitError, bool) {
+	var le *LimitError
+	ok := errors.As(err, &le)
+	return le, ok
+}
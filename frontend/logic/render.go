@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Render formats.
+const (
+	FormatPDF  = "pdf"
+	FormatPPTX = "pptx"
+	FormatDOCX = "docx"
+	FormatMD   = "md"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Rendered is a file produced from lesson content.
+type Rendered struct {
+	Data        []byte
+	Name        string
+	ContentType string
+}
+
+// DefaultFormat is the file produced at generation time for mode.
+func DefaultFormat(mode string) string {
+	if mode == "ppt" {
+		return FormatPPTX
+	}
+	return FormatMD
+}
+
+// Render produces content as a file in format.
+func Render(format, userID, content string) (Rendered, error) {
+	var out Rendered
+	var err error
+	switch format {
+	case FormatPPTX:
+		out.Data, out.Name, err = GeneratePPTX(userID, content)
+		out.ContentType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	case FormatPDF:
+		out.Data, out.Name, err = GeneratePDF(userID, content)
+		out.ContentType = "application/pdf"
+	case FormatDOCX:
+		out.Data, out.Name, err = GenerateDOCX(userID, content)
+		out.ContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case FormatMD:
+		out.Data = []byte(content)
+		out.Name = fmt.Sprintf("lesson_%s_%d.md", userID, time.Now().Unix())
+		out.ContentType = "text/markdown"
+	default:
+		return out, ErrUnknownFormat
+	}
+	return out, err
+}
@@ -0,0 +1,113 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrShareNotFound = errors.New("share not found or expired")
+
+type Share struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	Views     int        `json:"views"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// SharedLesson is the view-only copy of a generation shown to anyone with
+// the link. It deliberately leaves out the owner and storage paths.
+type SharedLesson struct {
+	GenerationID string `json:"-"`
+
+	Title     string    `json:"title"`
+	Prompt    string    `json:"prompt"`
+	Mode      string    `json:"mode"`
+	Grade     string    `json:"grade"`
+	Duration  string    `json:"duration"`
+	Content   string    `json:"content"`
+	Structure Document  `json:"structure"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateShare issues a share token for a generation. A nil expiresAt
+// means the link lives until revoked.
+func CreateShare(ctx context.Context, db DB, generationID, userID string, expiresAt *time.Time) (Share, error) {
+	token, err := randomCode(24)
+	if err != nil {
+		return Share{}, err
+	}
+	s := Share{Token: token, ExpiresAt: expiresAt}
+	err = db.QueryRow(ctx,
+		`INSERT INTO generation_shares (generation_id, created_by, token, expires_at)
+		 SELECT id, user_id, $3, $4 FROM generations WHERE id = $1::uuid AND user_id = $2::uuid
+		 RETURNING id, created_at`,
+		generationID, userID, token, expiresAt).Scan(&s.ID, &s.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrGenerationNotFound
+	}
+	return s, err
+}
+
+func ListShares(ctx context.Context, db DB, generationID string) ([]Share, error) {
+	rows, err := db.Query(ctx,
+		`SELECT id, token, expires_at, revoked_at, views, created_at FROM generation_shares
+		 WHERE generation_id = $1::uuid ORDER BY created_at DESC`, generationID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Share, error) {
+		var s Share
+		err := row.Scan(&s.ID, &s.Token, &s.ExpiresAt, &s.RevokedAt, &s.Views, &s.CreatedAt)
+		return s, err
+	})
+}
+
+func RevokeShare(ctx context.Context, db DB, generationID, shareID, userID string) error {
+	res, err := db.Exec(ctx,
+		`UPDATE generation_shares SET revoked_at = CURRENT_TIMESTAMP
+		 WHERE id = $1::uuid AND generation_id = $2::uuid AND created_by = $3::uuid AND revoked_at IS NULL`,
+		shareID, generationID, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// OpenShare resolves a live share token to its lesson. countView bumps
+// the share's view counter.
+func OpenShare(ctx context.Context, db DB, token string, countView bool) (SharedLesson, error) {
+	var l SharedLesson
+	var content *string
+	var structure *Document
+	err := db.QueryRow(ctx,
+		`SELECT g.id, g.prompt, COALESCE(g.mode, ''), COALESCE(g.grade, ''), COALESCE(g.duration, ''),
+		        g.raw_content, g.structure, g.created_at
+		 FROM generation_shares s JOIN generations g ON g.id = s.generation_id
+		 WHERE s.token = $1 AND s.revoked_at IS NULL
+		 AND (s.expires_at IS NULL OR s.expires_at > CURRENT_TIMESTAMP)`,
+		token).Scan(&l.GenerationID, &l.Prompt, &l.Mode, &l.Grade, &l.Duration, &content, &structure, &l.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && content == nil) {
+		return l, ErrShareNotFound
+	}
+	if err != nil {
+		return l, err
+	}
+	l.Content = *content
+	l.Structure = ParseContent(l.Mode, l.Content)
+	if structure != nil {
+		l.Structure = *structure
+	}
+	l.Title = l.Structure.Title
+	if countView {
+		db.Exec(ctx, "UPDATE generation_shares SET views = views + 1 WHERE token = $1", token)
+	}
+	return l, nil
+}
@@ -0,0 +1,76 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Storage stores generated files privately. Put must return an error if
+// the object was not durably written; SignedURL grants temporary read
+// access to a single object. Check reports whether the backend is
+// reachable and its bucket exists, without writing anything.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	Check(ctx context.Context) error
+}
+
+// DownloadURLTTL is how long download links handed to clients stay valid.
+const DownloadURLTTL = 5 * time.Minute
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// StorageConfig selects and configures a storage backend.
+type StorageConfig struct {
+	Backend     string // supabase, s3 or local
+	Bucket      string
+	SupabaseURL string
+	ServiceKey  string
+	S3Endpoint  string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	LocalDir    string
+	LocalURL    string
+	SigningKey  string
+}
+
+// NewStorage builds the backend named by c.Backend.
+func NewStorage(c StorageConfig) (Storage, error) {
+	switch c.Backend {
+	case "supabase":
+		s := &SupabaseStorage{BaseURL: c.SupabaseURL, ServiceKey: c.ServiceKey, Bucket: c.Bucket}
+		if s.BaseURL == "" || s.ServiceKey == "" {
+			return nil, errors.New("supabase storage needs SUPABASE_URL and SUPABASE_SERVICE_ROLE_KEY")
+		}
+		return s, nil
+	case "s3":
+		s := &S3Storage{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			Bucket:    c.Bucket,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+		}
+		if s.Endpoint == "" || s.AccessKey == "" || s.SecretKey == "" {
+			return nil, errors.New("s3 storage needs S3_ENDPOINT, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+		}
+		return s, nil
+	case "local":
+		key := []byte(c.SigningKey)
+		if len(key) == 0 {
+			// Links then only survive as long as this process, which is
+			// fine for a single local dev server.
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, err
+			}
+		}
+		return &LocalStorage{Dir: c.LocalDir, BaseURL: c.LocalURL, SigningKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", c.Backend)
+	}
+}
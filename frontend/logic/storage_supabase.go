@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SupabaseStorage writes to a Supabase Storage bucket with the service
+// role key.
+type SupabaseStorage struct {
+	BaseURL    string
+	ServiceKey string
+	Bucket     string
+	Client     *http.Client
+}
+
+func (s *SupabaseStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", strings.TrimSuffix(s.BaseURL, "/"), s.Bucket, key)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("supabase upload: status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// SignedURL asks Supabase to sign a time-limited download link; the bucket
+// itself stays private.
+func (s *SupabaseStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	base := strings.TrimSuffix(s.BaseURL, "/")
+	body, _ := json.Marshal(map[string]int{"expiresIn": int(ttl.Seconds())})
+	url := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s", base, s.Bucket, key)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("apikey", s.ServiceKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return "", ErrObjectNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("supabase sign: status %d", resp.StatusCode)
+	}
+	var out struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return base + "/storage/v1" + out.SignedURL, nil
+}
+
+func (s *SupabaseStorage) Check(ctx context.Context) error {
+	url := fmt.Sprintf("%s/storage/v1/bucket/%s", strings.TrimSuffix(s.BaseURL, "/"), s.Bucket)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ServiceKey)
+	req.Header.Set("apikey", s.ServiceKey)
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("supabase bucket %s: status %d", s.Bucket, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *SupabaseStorage) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ModelPrice is a model's list price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices are matched by longest prefix, so dated or preview variants
+// of a model are billed like the model itself. Image output from Gemini
+// is billed at a higher rate we don't track; those costs are understated.
+var modelPrices = map[string]ModelPrice{
+	"gemini-2.5-flash": {Input: 0.30, Output: 2.50},
+	"gemini-2.5-pro":   {Input: 1.25, Output: 10.00},
+	"deepseek-chat":    {Input: 0.28, Output: 0.42},
+	"mock":             {},
+}
+
+// PriceOf returns the price of model and whether it is known.
+func PriceOf(model string) (ModelPrice, bool) {
+	best, found := "", false
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) >= len(best) {
+			best, found = prefix, true
+		}
+	}
+	return modelPrices[best], found
+}
+
+// Usage is what the AI calls behind a generation or version consumed, as
+// stored with it. Retries and moderation calls are added in with Add.
+type Usage struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	LatencyMS    int64   `json:"latencyMs"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// UsageOf prices a completion from provider. Unknown models cost 0.
+func UsageOf(provider string, c Completion) Usage {
+	p, _ := PriceOf(c.Model)
+	return Usage{
+		Provider:     provider,
+		Model:        c.Model,
+		InputTokens:  c.InputTokens,
+		OutputTokens: c.OutputTokens,
+		LatencyMS:    c.Latency.Milliseconds(),
+		CostUSD:      (float64(c.InputTokens)*p.Input + float64(c.OutputTokens)*p.Output) / 1e6,
+	}
+}
+
+// Add counts another call in u. Provider and model are those of the
+// first call.
+func (u *Usage) Add(o Usage) {
+	if u.Provider == "" {
+		u.Provider, u.Model = o.Provider, o.Model
+	}
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.LatencyMS += o.LatencyMS
+	u.CostUSD += o.CostUSD
+}
+
+// usageColumns splits u into the provider .. cost_usd columns, all NULL
+// when u is nil.
+func usageColumns(u *Usage) []any {
+	if u == nil {
+		return []any{nil, nil, nil, nil, nil, nil}
+	}
+	return []any{u.Provider, u.Model, u.InputTokens, u.OutputTokens, u.LatencyMS, u.CostUSD}
+}
+
+// UsageRow is one day's AI usage for one user and mode.
+type UsageRow struct {
+	Day          string  `json:"day"`
+	UserID       string  `json:"userId"`
+	Email        string  `json:"email"`
+	Mode         string  `json:"mode"`
+	Calls        int     `json:"calls"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// UsageReport totals AI calls (generations and section edits) made in
+// [from, to) per UTC day, user and mode, most recent and costliest first.
+func UsageReport(ctx context.Context, db DB, from, to time.Time) ([]UsageRow, error) {
+	rows, err := db.Query(ctx,
+		`WITH calls AS (
+		     SELECT user_id, mode, created_at, input_tokens, output_tokens, cost_usd
+		     FROM generations WHERE model IS NOT NULL
+		     UNION ALL
+		     SELECT g.user_id, g.mode, v.created_at, v.input_tokens, v.output_tokens, v.cost_usd
+		     FROM generation_versions v JOIN generations g ON g.id = v.generation_id
+		     WHERE v.model IS NOT NULL
+		 )
+		 SELECT to_char(c.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, c.user_id, COALESCE(u.email, ''),
+		        COALESCE(c.mode, ''), count(*), COALESCE(sum(c.input_tokens), 0), COALESCE(sum(c.output_tokens), 0),
+		        COALESCE(sum(c.cost_usd), 0)::float8 AS cost
+		 FROM calls c LEFT JOIN users u ON u.id = c.user_id
+		 WHERE c.created_at >= $1 AND c.created_at < $2
+		 GROUP BY 1, 2, 3, 4
+		 ORDER BY day DESC, cost DESC`,
+		from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (UsageRow, error) {
+		var u UsageRow
+		err := row.Scan(&u.Day, &u.UserID, &u.Email, &u.Mode, &u.Calls, &u.InputTokens, &u.OutputTokens, &u.CostUSD)
+		return u, err
+	})
+}
@@ -0,0 +1,157 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// Version is one saved revision of a generation's content and the file
+// rendered from it.
+type Version struct {
+	Version   int       `json:"version"`
+	Note      string    `json:"note"`
+	FilePath  string    `json:"filePath"`
+	Content   string    `json:"content,omitempty"`
+	Structure *Document `json:"structure,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Moderation is the verdict the version's content was saved with,
+	// carried over when it is restored.
+	Moderation *safety.Moderation `json:"-"`
+}
+
+// SaveVersion records g's current content, with g.Usage and
+// g.TemplateVersion if an AI call produced it and g.Moderation if it was
+// checked (restores carry the old version's verdict), as a new version and
+// makes it the generation's live content. Generations saved before versioning
+// get their previous content backfilled as version 1 first.
+func SaveVersion(ctx context.Context, db DB, g Generation, note string) (int, error) {
+	var version int
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		// Lock the generation so concurrent edits get distinct numbers.
+		if _, err := tx.Exec(ctx, "SELECT 1 FROM generations WHERE id = $1::uuid FOR UPDATE", g.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO generation_versions (generation_id, version, content, structure, file_path, note, moderation)
+			 SELECT id, 1, raw_content, structure, file_path, 'initial', moderation FROM generations
+			 WHERE id = $1::uuid AND raw_content IS NOT NULL
+			 AND NOT EXISTS (SELECT 1 FROM generation_versions WHERE generation_id = $1::uuid)`,
+			g.ID); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO generation_versions (generation_id, version, content, structure, file_path, note,
+			                                  template_version, moderation, provider, model, input_tokens, output_tokens, latency_ms, cost_usd)
+			 SELECT $1::uuid, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13
+			 FROM generation_versions WHERE generation_id = $1::uuid
+			 RETURNING version`,
+			append([]any{g.ID, g.Content, g.Structure, g.FilePath, note, g.TemplateVersion, g.Moderation}, usageColumns(g.Usage)...)...).Scan(&version); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			"UPDATE generations SET file_path = $2, raw_content = $3, structure = $4, current_version = $5, moderation = $6 WHERE id = $1::uuid",
+			g.ID, g.FilePath, g.Content, g.Structure, version, g.Moderation)
+		return err
+	})
+	return version, err
+}
+
+// ListVersions returns a generation's versions, newest first, without
+// their content.
+func ListVersions(ctx context.Context, db DB, generationID string) ([]Version, error) {
+	rows, err := db.Query(ctx,
+		`SELECT version, note, COALESCE(file_path, ''), created_at FROM generation_versions
+		 WHERE generation_id = $1::uuid ORDER BY version DESC`, generationID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Version, error) {
+		var v Version
+		err := row.Scan(&v.Version, &v.Note, &v.FilePath, &v.CreatedAt)
+		return v, err
+	})
+}
+
+func GetVersion(ctx context.Context, db DB, generationID string, version int) (Version, error) {
+	v := Version{Version: version}
+	var filePath *string
+	err := db.QueryRow(ctx,
+		`SELECT note, file_path, content, structure, created_at, moderation FROM generation_versions
+		 WHERE generation_id = $1::uuid AND version = $2`,
+		generationID, version).Scan(&v.Note, &filePath, &v.Content, &v.Structure, &v.CreatedAt, &v.Moderation)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return v, ErrVersionNotFound
+	}
+	if filePath != nil {
+		v.FilePath = *filePath
+	}
+	return v, err
+}
+
+// SectionDiff describes how one section changed between two versions.
+type SectionDiff struct {
+	Status string   `json:"status"` // unchanged, changed, added, removed
+	Title  string   `json:"title"`
+	From   *Section `json:"from,omitempty"`
+	To     *Section `json:"to,omitempty"`
+}
+
+// DiffDocuments aligns the sections of a and b by title and reports which
+// were kept, edited, added or removed. A removal directly followed by an
+// addition is reported as a change, which covers slides whose title was
+// rewritten along with their body.
+func DiffDocuments(a, b Document) []SectionDiff {
+	key := func(s Section) string { return strings.ToLower(strings.TrimSpace(s.Title)) }
+	n, m := len(a.Sections), len(b.Sections)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if key(a.Sections[i]) == key(b.Sections[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []SectionDiff
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && key(a.Sections[i]) == key(b.Sections[j]):
+			from, to := a.Sections[i], b.Sections[j]
+			status := "unchanged"
+			if from.Title != to.Title || !slices.Equal(from.Lines, to.Lines) {
+				status = "changed"
+			}
+			out = append(out, SectionDiff{Status: status, Title: to.Title, From: &from, To: &to})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			from := a.Sections[i]
+			out = append(out, SectionDiff{Status: "removed", Title: from.Title, From: &from})
+			i++
+		default:
+			to := b.Sections[j]
+			if k := len(out) - 1; k >= 0 && out[k].Status == "removed" {
+				out[k] = SectionDiff{Status: "changed", Title: to.Title, From: out[k].From, To: &to}
+			} else {
+				out = append(out, SectionDiff{Status: "added", Title: to.Title, To: &to})
+			}
+			j++
+		}
+	}
+	return out
+}
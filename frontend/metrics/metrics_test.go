@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteGolden(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("lf_generations_total", "Generations by mode\nand outcome, with a \\ backslash.", "mode", "outcome")
+	c.Inc("lesson", "ok")
+	c.Add(2.5, "lesson", "ok")
+	c.Inc("ppt", `bad "quote"`)
+	c.Inc("ppt", "back\\slash\nnewline")
+
+	h := r.NewHistogramVec("lf_request_seconds", "Request latency.", []float64{0.25, 1, 10}, "route")
+	for _, v := range []float64{0.125, 0.25, 0.5, 20} {
+		h.Observe(v, "/api/generate")
+	}
+	h.Observe(1, `/api/"x"`)
+
+	r.NewGaugeFunc("lf_pool_idle", "Idle connections.", func() float64 { return 3 })
+
+	var b strings.Builder
+	r.Write(&b)
+	want := `# HELP lf_generations_total Generations by mode\nand outcome, with a \\ backslash.
+# TYPE lf_generations_total counter
+lf_generations_total{mode="lesson",outcome="ok"} 3.5
+lf_generations_total{mode="ppt",outcome="back\\slash\nnewline"} 1
+lf_generations_total{mode="ppt",outcome="bad \"quote\""} 1
+# HELP lf_request_seconds Request latency.
+# TYPE lf_request_seconds histogram
+lf_request_seconds_bucket{route="/api/\"x\"",le="0.25"} 0
+lf_request_seconds_bucket{route="/api/\"x\"",le="1"} 1
+lf_request_seconds_bucket{route="/api/\"x\"",le="10"} 1
+lf_request_seconds_bucket{route="/api/\"x\"",le="+Inf"} 1
+lf_request_seconds_sum{route="/api/\"x\""} 1
+lf_request_seconds_count{route="/api/\"x\""} 1
+lf_request_seconds_bucket{route="/api/generate",le="0.25"} 2
+lf_request_seconds_bucket{route="/api/generate",le="1"} 3
+lf_request_seconds_bucket{route="/api/generate",le="10"} 3
+lf_request_seconds_bucket{route="/api/generate",le="+Inf"} 4
+lf_request_seconds_sum{route="/api/generate"} 20.875
+lf_request_seconds_count{route="/api/generate"} 4
+# HELP lf_pool_idle Idle connections.
+# TYPE lf_pool_idle gauge
+lf_pool_idle 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	c := NewRegistry().NewCounterVec("x_total", "x", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with one label value for two labels did not panic")
+		}
+	}()
+	c.Inc("only")
+}
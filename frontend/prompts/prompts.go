@@ -0,0 +1,266 @@
+// Package prompts holds the AI prompt templates. Templates live in
+// templates/ as text/template files named
+//
+//	<name>.<locale>.<band>.v<version>.tmpl
+//
+// where name is one of the template names below, locale is a language tag
+// such as es or pt-BR, band is a grade band (see GradeBand), and either
+// may be "any". A request gets the most specific template that
+// matches its locale and grade.
+//
+// Several versions of one template can run side by side for A/B tests: a
+// template may start with {{/* weight: N */}} to take a share of traffic,
+// and users are split between versions by a stable hash. Without weights
+// the newest version gets everything. The chosen template's ID is stored
+// with each generation.
+package prompts
+
+import (
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Template names.
+const (
+	Lesson        = "lesson"
+	PPT           = "ppt"
+	LessonSection = "lesson_section"
+	PPTSlide      = "ppt_slide"
+	Moderation    = "moderation"
+	Rewrite       = "rewrite"
+)
+
+// Any matches every locale or grade band.
+const Any = "any"
+
+// Data is what templates can refer to. Section edits also fill in the
+// section fields, and readability rewrites the last two.
+type Data struct {
+	Topic    string
+	Grade    string
+	Duration string
+	Locale   string // e.g. es-MX; empty when unknown
+	Language string // base language of Locale, e.g. es
+
+	Index       int    // 1-based position of the section being edited
+	Title       string // its title
+	Section     string // its current text
+	Instruction string // what the user wants changed
+	Content     string // the whole document, for context
+
+	Adjust       string  // simplify or enrich
+	ReadingGrade float64 // the grade Content reads at now
+}
+
+// Template is one version of a prompt.
+type Template struct {
+	Name    string
+	Locale  string
+	Band    string
+	Version int
+	Weight  int
+	tmpl    *template.Template
+}
+
+// ID identifies the template version, e.g. "lesson.any.k-2.v3".
+func (t *Template) ID() string {
+	return fmt.Sprintf("%s.%s.%s.v%d", t.Name, t.Locale, t.Band, t.Version)
+}
+
+// Execute renders the prompt.
+func (t *Template) Execute(d Data) (string, error) {
+	if d.Language == "" {
+		d.Language, _, _ = strings.Cut(d.Locale, "-")
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("prompt %s: %w", t.ID(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+type key struct{ name, locale, band string }
+
+// Registry selects templates. It is safe for concurrent use.
+type Registry struct {
+	byKey map[key][]*Template
+}
+
+// Default returns the registry of embedded templates. It panics if they
+// don't load, which would be a bug in this package.
+var Default = sync.OnceValue(func() *Registry {
+	r, err := Load(templateFiles, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return r
+})
+
+var (
+	fileName = regexp.MustCompile(`^([a-z_]+)\.([A-Za-z0-9-]+)\.([a-z0-9-]+)\.v([0-9]+)\.tmpl$`)
+	weightRe = regexp.MustCompile(`^\{\{/\*\s*weight:\s*([0-9]+)\s*\*/\}\}`)
+)
+
+// Load parses every template in dir.
+func Load(fsys fs.FS, dir string) (*Registry, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{byKey: map[key][]*Template{}}
+	weighted := map[key]bool{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("prompt template %s: name must be <name>.<locale>.<band>.v<N>.tmpl", e.Name())
+		}
+		src, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		t := &Template{Name: m[1], Locale: strings.ToLower(m[2]), Band: m[3]}
+		t.Version, _ = strconv.Atoi(m[4])
+		if w := weightRe.FindSubmatch(src); w != nil {
+			t.Weight, _ = strconv.Atoi(string(w[1]))
+			weighted[key{t.Name, t.Locale, t.Band}] = true
+		}
+		t.tmpl, err = template.New(e.Name()).Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %s: %w", e.Name(), err)
+		}
+		k := key{t.Name, t.Locale, t.Band}
+		r.byKey[k] = append(r.byKey[k], t)
+	}
+	for k, ts := range r.byKey {
+		sort.Slice(ts, func(i, j int) bool { return ts[i].Version < ts[j].Version })
+		for i := 1; i < len(ts); i++ {
+			if ts[i].Version == ts[i-1].Version {
+				return nil, fmt.Errorf("prompt template %s: duplicate version", ts[i].ID())
+			}
+		}
+		if !weighted[k] {
+			ts[len(ts)-1].Weight = 1
+		}
+	}
+	return r, nil
+}
+
+// Select picks the template for name, preferring an exact locale over its
+// base language over any, then a matching grade band over any. seed (the
+// user ID) keeps each user on the same version while an A/B test runs.
+func (r *Registry) Select(name, locale, grade, seed string) (*Template, error) {
+	locale = strings.ToLower(locale)
+	base, _, _ := strings.Cut(locale, "-")
+	band := GradeBand(grade)
+	for _, loc := range []string{locale, base, Any} {
+		for _, b := range []string{band, Any} {
+			if loc == "" || b == "" {
+				continue
+			}
+			if t := pick(r.byKey[key{name, loc, b}], name+"/"+seed); t != nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no prompt template for %s", name)
+}
+
+// pick chooses among versions by weight, stably for a given seed.
+func pick(ts []*Template, seed string) *Template {
+	total := 0
+	for _, t := range ts {
+		total += t.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	n := int(h.Sum32() % uint32(total))
+	for _, t := range ts {
+		if n < t.Weight {
+			return t
+		}
+		n -= t.Weight
+	}
+	return nil
+}
+
+var (
+	gradeNumber = regexp.MustCompile(`[0-9]+`)
+	// preK matches pre-k, prek, pre k and preschool, but not course names
+	// like Pre-Calculus or Pre-AP.
+	preK = regexp.MustCompile(`^pre[- ]?(k|school)\b`)
+)
+
+// GradeBand maps a free-text grade level ("Grade 4", "10th", "K") to one
+// of k-2, 3-5, 6-8, 9-12 or adult, or "" when it can't tell.
+func GradeBand(grade string) string {
+	n, ok := GradeLevel(grade)
+	switch {
+	case ok && n <= 2:
+		return "k-2"
+	case ok && n <= 5:
+		return "3-5"
+	case ok && n <= 8:
+		return "6-8"
+	case ok:
+		return "9-12"
+	case isAdult(grade):
+		return "adult"
+	}
+	return ""
+}
+
+// GradeLevel maps a free-text K-12 grade level to its number, with
+// kindergarten and pre-K as 0. It reports false for adult levels and
+// anything it can't read.
+func GradeLevel(grade string) (int, bool) {
+	g := strings.ToLower(strings.TrimSpace(grade))
+	switch {
+	case g == "" || isAdult(g):
+		return 0, false
+	case g == "k" || strings.Contains(g, "kinder") || preK.MatchString(g) || strings.HasPrefix(g, "k-") || strings.HasPrefix(g, "k "):
+		return 0, true
+	}
+	n, err := strconv.Atoi(gradeNumber.FindString(g))
+	if err != nil || n > 12 {
+		return 0, false
+	}
+	return n, true
+}
+
+func isAdult(grade string) bool {
+	g := strings.ToLower(grade)
+	if strings.Contains(g, "college") || strings.Contains(g, "university") || strings.Contains(g, "adult") || strings.Contains(g, "higher ed") {
+		return true
+	}
+	n, err := strconv.Atoi(gradeNumber.FindString(g))
+	return err == nil && n > 12
+}
+
+var localeRe = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// NormalizeLocale returns tag if it looks like a language tag, or the
+// first language in an Accept-Language header, and "" otherwise.
+func NormalizeLocale(tag string) string {
+	tag, _, _ = strings.Cut(tag, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.TrimSpace(tag)
+	if !localeRe.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,168 @@
+package prompts
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGradeLevel(t *testing.T) {
+	cases := []struct {
+		grade string
+		level int
+		ok    bool
+		band  string
+	}{
+		{"K", 0, true, "k-2"},
+		{"Kindergarten", 0, true, "k-2"},
+		{"Pre-K", 0, true, "k-2"},
+		{"prek", 0, true, "k-2"},
+		{"Pre K", 0, true, "k-2"},
+		{"Preschool", 0, true, "k-2"},
+		{"Pre-Kindergarten", 0, true, "k-2"},
+		{"K-2", 0, true, "k-2"},
+		{"Grade 1", 1, true, "k-2"},
+		{"3rd", 3, true, "3-5"},
+		{"Grade 5", 5, true, "3-5"},
+		{"6th grade", 6, true, "6-8"},
+		{"8", 8, true, "6-8"},
+		{"9th", 9, true, "9-12"},
+		{"12", 12, true, "9-12"},
+		{"Pre-Calculus", 0, false, ""},
+		{"Pre-Calculus (11th)", 11, true, "9-12"},
+		{"Pre-AP Biology, Grade 10", 10, true, "9-12"},
+		{"Pre-Algebra", 0, false, ""},
+		{"College", 0, false, "adult"},
+		{"Adult learners", 0, false, "adult"},
+		{"13", 0, false, "adult"},
+		{"", 0, false, ""},
+		{"mixed", 0, false, ""},
+	}
+	for _, c := range cases {
+		level, ok := GradeLevel(c.grade)
+		if level != c.level || ok != c.ok {
+			t.Errorf("GradeLevel(%q) = %d, %v; want %d, %v", c.grade, level, ok, c.level, c.ok)
+		}
+		if band := GradeBand(c.grade); band != c.band {
+			t.Errorf("GradeBand(%q) = %q, want %q", c.grade, band, c.band)
+		}
+	}
+}
+
+func testRegistry(t *testing.T, files map[string]string) *Registry {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	for name, src := range files {
+		fsys["t/"+name] = &fstest.MapFile{Data: []byte(src)}
+	}
+	r, err := Load(fsys, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestSelect(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"lesson.any.any.v1.tmpl":   "any",
+		"lesson.any.k-2.v1.tmpl":   "k-2",
+		"lesson.es.any.v1.tmpl":    "es",
+		"lesson.es.k-2.v1.tmpl":    "es k-2",
+		"lesson.pt-br.any.v1.tmpl": "pt-BR",
+		"lesson.any.any.v2.tmpl":   "any v2",
+	})
+	cases := []struct {
+		locale, grade, want string
+	}{
+		{"", "Grade 7", "lesson.any.any.v2"},
+		{"", "Grade 1", "lesson.any.k-2.v1"},
+		{"en-US", "", "lesson.any.any.v2"},
+		{"es", "Grade 7", "lesson.es.any.v1"},
+		{"es-MX", "Grade 7", "lesson.es.any.v1"},
+		{"es-MX", "K", "lesson.es.k-2.v1"},
+		{"pt-BR", "K", "lesson.pt-br.any.v1"},
+		{"PT-br", "Grade 4", "lesson.pt-br.any.v1"},
+		{"pt", "Grade 4", "lesson.any.any.v2"},
+		{"fr", "Pre-K", "lesson.any.k-2.v1"},
+		{"fr", "Pre-Calculus", "lesson.any.any.v2"},
+	}
+	for _, c := range cases {
+		tmpl, err := r.Select(Lesson, c.locale, c.grade, "user-1")
+		if err != nil {
+			t.Errorf("Select(%q, %q): %v", c.locale, c.grade, err)
+			continue
+		}
+		if tmpl.ID() != c.want {
+			t.Errorf("Select(%q, %q) = %s, want %s", c.locale, c.grade, tmpl.ID(), c.want)
+		}
+	}
+	if _, err := r.Select(PPT, "", "", "user-1"); err == nil {
+		t.Error("Select of a missing template succeeded")
+	}
+}
+
+func TestWeightedPick(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"lesson.any.any.v1.tmpl": "{{/* weight: 3 */}}old",
+		"lesson.any.any.v2.tmpl": "{{/* weight: 1 */}}new",
+		"lesson.any.any.v3.tmpl": "{{/* weight: 0 */}}draft",
+	})
+	counts := map[int]int{}
+	for i := 0; i < 4000; i++ {
+		seed := fmt.Sprintf("user-%d", i)
+		first, _ := r.Select(Lesson, "", "", seed)
+		again, _ := r.Select(Lesson, "", "", seed)
+		if first != again {
+			t.Fatalf("seed %q got %s then %s", seed, first.ID(), again.ID())
+		}
+		counts[first.Version]++
+	}
+	if counts[3] != 0 {
+		t.Errorf("weight 0 version picked %d times", counts[3])
+	}
+	if share := float64(counts[1]) / 4000; share < 0.7 || share > 0.8 {
+		t.Errorf("weight 3 of 4 got %.2f of traffic, counts %v", share, counts)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"lesson.any.any.v1.tmpl":  "Old {{.Topic}}",
+		"lesson.any.any.v10.tmpl": "Teach {{.Topic}} in {{.Language}}.\n",
+	})
+	tmpl, err := r.Select(Lesson, "", "", "u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID() != "lesson.any.any.v10" || tmpl.Weight != 1 {
+		t.Errorf("unweighted templates: got %s with weight %d, want the newest with weight 1", tmpl.ID(), tmpl.Weight)
+	}
+	out, err := tmpl.Execute(Data{Topic: "fractions", Locale: "es-MX"})
+	if err != nil || out != "Teach fractions in es." {
+		t.Errorf("Execute = %q, %v", out, err)
+	}
+
+	bad := map[string]map[string]string{
+		"bad name":          {"lesson.tmpl": "x"},
+		"missing version":   {"lesson.any.any.tmpl": "x"},
+		"duplicate version": {"lesson.any.any.v1.tmpl": "x", "lesson.any.any.v01.tmpl": "y"},
+		"parse error":       {"lesson.any.any.v1.tmpl": "{{.Topic"},
+	}
+	for name, files := range bad {
+		fsys := fstest.MapFS{}
+		for f, src := range files {
+			fsys["t/"+f] = &fstest.MapFile{Data: []byte(src)}
+		}
+		if _, err := Load(fsys, "t"); err == nil {
+			t.Errorf("%s: Load succeeded", name)
+		}
+	}
+}
+
+func TestDefaultTemplatesLoad(t *testing.T) {
+	for _, name := range []string{Lesson, PPT, LessonSection, PPTSlide, Moderation, Rewrite} {
+		if _, err := Default().Select(name, "", "", "u"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package readability measures how hard English text is to read, as a US
+// school grade. It understands the Markdown lessons are written in: each
+// heading and list item counts as a sentence of its own, and formatting
+// and link targets are ignored.
+package readability
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MinGrade is the lowest level the formulas tell apart well. Targets
+// below it are compared as if they were MinGrade.
+const MinGrade = 3
+
+// Stats describes a text. Grades are rounded to one decimal.
+type Stats struct {
+	Sentences         int `json:"sentences"`
+	Words             int `json:"words"`
+	Syllables         int `json:"syllables"`
+	PolysyllableWords int `json:"polysyllableWords"` // three syllables or more
+
+	WordsPerSentence  float64 `json:"wordsPerSentence"`
+	SyllablesPerWord  float64 `json:"syllablesPerWord"`
+	LettersPerWord    float64 `json:"lettersPerWord"`
+	LongestSentence   int     `json:"longestSentence"` // in words
+	FleschReadingEase float64 `json:"fleschReadingEase"`
+
+	FleschKincaid float64 `json:"fleschKincaid"`
+	SMOG          float64 `json:"smog"`
+	// Grade is the mean of the Flesch-Kincaid and SMOG grades, which
+	// err in opposite directions on short, list-heavy text.
+	Grade float64 `json:"grade"`
+}
+
+var (
+	markdownMarks = regexp.MustCompile(`(?m)^\s*(#+|[-*+]|\d+[.)]|>)\s+|[*_` + "`" + `]+|!?\[([^\]]*)\]\([^)]*\)`)
+	sentenceEnd   = regexp.MustCompile(`[.!?]+(\s|$)`)
+	vowelGroup    = regexp.MustCompile(`[aeiouy]+`)
+)
+
+// Analyze measures Markdown text. Text with no words gives zero Stats.
+func Analyze(text string) Stats {
+	text = markdownMarks.ReplaceAllString(text, "$2")
+	var s Stats
+	letters := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Trim(line, "-=|: ") == "" {
+			continue
+		}
+		ends := sentenceEnd.FindAllStringIndex(line, -1)
+		if !strings.ContainsAny(line[len(line)-1:], ".!?") {
+			ends = append(ends, []int{len(line), len(line)}) // a heading or bullet without a full stop
+		}
+		start := 0
+		for _, end := range ends {
+			n := 0
+			for _, w := range strings.FieldsFunc(line[start:end[1]], notWordRune) {
+				syl := syllables(w)
+				n++
+				s.Syllables += syl
+				letters += len([]rune(w))
+				if syl >= 3 {
+					s.PolysyllableWords++
+				}
+			}
+			start = end[1]
+			if n == 0 {
+				continue
+			}
+			s.Sentences++
+			s.Words += n
+			s.LongestSentence = max(s.LongestSentence, n)
+		}
+	}
+	if s.Words == 0 {
+		return Stats{}
+	}
+
+	wps := float64(s.Words) / float64(s.Sentences)
+	spw := float64(s.Syllables) / float64(s.Words)
+	s.WordsPerSentence = round(wps)
+	s.SyllablesPerWord = round(spw)
+	s.LettersPerWord = round(float64(letters) / float64(s.Words))
+	s.FleschReadingEase = round(206.835 - 1.015*wps - 84.6*spw)
+	fk := 0.39*wps + 11.8*spw - 15.59
+	smog := 1.0430*math.Sqrt(float64(s.PolysyllableWords)*30/float64(s.Sentences)) + 3.1291
+	s.FleschKincaid = round(max(fk, 0))
+	s.SMOG = round(smog)
+	s.Grade = round(max((fk+smog)/2, 0))
+	return s
+}
+
+// English reports whether text in locale ("" when unknown) can be
+// measured; the formulas are calibrated for English only.
+func English(locale string) bool {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	return lang == "" || lang == "en"
+}
+
+// Adjustment is how text should change to suit its target grade.
+type Adjustment string
+
+const (
+	OnTarget Adjustment = ""
+	Simplify Adjustment = "simplify"
+	Enrich   Adjustment = "enrich"
+)
+
+// Compare says whether a text measured at grade reads more than tolerance
+// grades off target.
+func Compare(grade float64, target, tolerance int) Adjustment {
+	t := float64(max(target, MinGrade))
+	switch {
+	case grade > t+float64(tolerance):
+		return Simplify
+	case grade < t-float64(tolerance):
+		return Enrich
+	}
+	return OnTarget
+}
+
+func notWordRune(r rune) bool { return !unicode.IsLetter(r) && r != '\'' }
+
+// syllables estimates the syllables in an English word by counting vowel
+// groups, less a silent final e.
+func syllables(word string) int {
+	w := strings.ToLower(strings.Trim(word, "'"))
+	n := len(vowelGroup.FindAllString(w, -1))
+	if strings.HasSuffix(w, "e") && !strings.HasSuffix(w, "le") && n > 1 {
+		n--
+	}
+	return max(n, 1)
+}
+
+func round(x float64) float64 { return math.Round(x*10) / 10 }
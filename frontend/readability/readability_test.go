@@ -0,0 +1,104 @@
+package readability
+
+import "testing"
+
+// The reference texts are short enough to count by hand; the expected
+// grades follow from the counts and the published Flesch-Kincaid,
+// Flesch Reading Ease and SMOG formulas.
+func TestAnalyzeReferenceTexts(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want Stats
+	}{
+		{
+			name: "monosyllables",
+			text: "The cat sat on the mat. The dog ran to the park.",
+			// 2 sentences, 12 words, 12 syllables, 35 letters:
+			// FK = 0.39*6 + 11.8*1 - 15.59 = -1.45, shown as 0,
+			// SMOG = 1.0430*sqrt(0) + 3.1291, Grade = (-1.45 + 3.13) / 2.
+			want: Stats{
+				Sentences: 2, Words: 12, Syllables: 12,
+				WordsPerSentence: 6, SyllablesPerWord: 1, LettersPerWord: 2.9, LongestSentence: 6,
+				FleschReadingEase: 116.1, FleschKincaid: 0, SMOG: 3.1, Grade: 0.8,
+			},
+		},
+		{
+			name: "polysyllables",
+			text: "Photosynthesis transforms electromagnetic radiation into chemical energy. " +
+				"Consequently, vegetation supports practically every ecosystem.",
+			// 2 sentences, 13 words, 45 syllables, 10 of three or more,
+			// 121 letters:
+			// FK = 0.39*6.5 + 11.8*45/13 - 15.59 = 27.8,
+			// SMOG = 1.0430*sqrt(10*30/2) + 3.1291 = 15.9.
+			want: Stats{
+				Sentences: 2, Words: 13, Syllables: 45, PolysyllableWords: 10,
+				WordsPerSentence: 6.5, SyllablesPerWord: 3.5, LettersPerWord: 9.3, LongestSentence: 7,
+				FleschReadingEase: -92.6, FleschKincaid: 27.8, SMOG: 15.9, Grade: 21.8,
+			},
+		},
+		{
+			name: "markdown",
+			text: "# Water Cycle\n- Rain falls down\n- See [the sun](https://example.com/evaporation-diagram) **rise**.\n\n| --- | --- |\n",
+			// Heading and bullets are sentences; the link target, emphasis
+			// and table rule are ignored: 3 sentences, 9 words, 11 syllables,
+			// 36 letters. FK = 0.39*3 + 11.8*11/9 - 15.59 = 0.002.
+			want: Stats{
+				Sentences: 3, Words: 9, Syllables: 11,
+				WordsPerSentence: 3, SyllablesPerWord: 1.2, LettersPerWord: 4, LongestSentence: 4,
+				FleschReadingEase: 100.4, FleschKincaid: 0, SMOG: 3.1, Grade: 1.6,
+			},
+		},
+		{name: "empty", text: "  \n---\n", want: Stats{}},
+	}
+	for _, c := range cases {
+		if got := Analyze(c.text); got != c.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	cases := map[string]int{
+		"cat": 1, "rise": 1, "make": 1, "the": 1, "table": 2, "little": 2,
+		"water": 2, "every": 3, "energy": 3, "radiation": 3, "photosynthesis": 5,
+		"don't": 1, "'quoted'": 2, "rhythm": 1,
+	}
+	for word, want := range cases {
+		if got := syllables(word); got != want {
+			t.Errorf("syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		grade     float64
+		target    int
+		tolerance int
+		want      Adjustment
+	}{
+		{6, 4, 2, OnTarget},
+		{6.1, 4, 2, Simplify},
+		{1.9, 4, 2, Enrich},
+		{2, 4, 2, OnTarget},
+		{5, 0, 2, OnTarget}, // kindergarten is compared as MinGrade
+		{5.1, 0, 2, Simplify},
+		{0, 1, 2, Enrich},
+		{0, 1, 3, OnTarget},
+		{12, 12, 0, OnTarget},
+	}
+	for _, c := range cases {
+		if got := Compare(c.grade, c.target, c.tolerance); got != c.want {
+			t.Errorf("Compare(%v, %d, %d) = %q, want %q", c.grade, c.target, c.tolerance, got, c.want)
+		}
+	}
+}
+
+func TestEnglish(t *testing.T) {
+	for locale, want := range map[string]bool{"": true, "en": true, "en-GB": true, "EN-us": true, "es": false, "pt-BR": false, "eng": false} {
+		if got := English(locale); got != want {
+			t.Errorf("English(%q) = %v, want %v", locale, got, want)
+		}
+	}
+}
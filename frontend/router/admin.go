@@ -0,0 +1,93 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+// adminOnly lets through only the users listed in ADMIN_USER_IDS.
+func (s *Server) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(s.cfg.AdminUserIDs, currentUser(r).ID) {
+			httpError(w, r, "Admins only", 403)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleUsageReport serves AI usage and estimated cost per day, user and
+// mode: GET /api/admin/usage?from=&to= where from/to are dates
+// (YYYY-MM-DD), to is inclusive, and the default is the last 30 days.
+func (s *Server) handleUsageReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -29)
+	var err error
+	if v := q.Get("from"); v != "" && err == nil {
+		from, err = time.Parse(time.DateOnly, v)
+	}
+	if v := q.Get("to"); v != "" && err == nil {
+		to, err = time.Parse(time.DateOnly, v)
+	}
+	if err != nil || to.Before(from) {
+		httpError(w, r, "Invalid date range", 400)
+		return
+	}
+
+	rows, err := logic.UsageReport(r.Context(), s.pool, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		logFor(r).Error("usage report failed", "err", err)
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	var total struct {
+		Calls        int     `json:"calls"`
+		InputTokens  int64   `json:"inputTokens"`
+		OutputTokens int64   `json:"outputTokens"`
+		CostUSD      float64 `json:"costUsd"`
+	}
+	for _, row := range rows {
+		total.Calls += row.Calls
+		total.InputTokens += row.InputTokens
+		total.OutputTokens += row.OutputTokens
+		total.CostUSD += row.CostUSD
+	}
+	writeJSON(w, 200, map[string]interface{}{
+		"from":  from.Format(time.DateOnly),
+		"to":    to.Format(time.DateOnly),
+		"rows":  rows,
+		"total": total,
+	})
+}
+
+// handleListFlags lists requests the input screening stage flagged,
+// newest first: GET /api/admin/flags, with ?all=1 to include those
+// already reviewed.
+func (s *Server) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := logic.ListFlaggedRequests(r.Context(), s.pool, r.URL.Query().Get("all") == "1", 100)
+	if err != nil {
+		logFor(r).Error("listing flagged requests failed", "err", err)
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"flags": flags})
+}
+
+// handleReviewFlag marks a flagged request as reviewed.
+func (s *Server) handleReviewFlag(w http.ResponseWriter, r *http.Request) {
+	err := logic.ReviewFlaggedRequest(r.Context(), s.pool, r.PathValue("flagID"), currentUser(r).ID)
+	if errors.Is(err, logic.ErrFlagNotFound) {
+		httpError(w, r, "Flagged request not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
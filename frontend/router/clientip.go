@@ -0,0 +1,83 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// proxyTrust decides how far to believe X-Forwarded-For. Only hops added
+// by a trusted proxy count: starting from the peer, each trusted address
+// lets us read one more entry from the right of the header, and the first
+// untrusted one is the client. Entries further left were written by the
+// client and could say anything.
+type proxyTrust struct {
+	// anyPeer trusts whatever connects to us, for platforms such as Vercel
+	// where the app is only reachable through the platform's proxy.
+	anyPeer bool
+	nets    []netip.Prefix
+}
+
+// newProxyTrust parses TRUSTED_PROXIES entries: "*", CIDRs or single
+// addresses. Config validation has already rejected anything else.
+func newProxyTrust(entries []string) *proxyTrust {
+	p := &proxyTrust{}
+	for _, e := range entries {
+		if e == "*" {
+			p.anyPeer = true
+			continue
+		}
+		if pfx, err := netip.ParsePrefix(e); err == nil {
+			p.nets = append(p.nets, pfx.Masked())
+		} else if addr, err := netip.ParseAddr(e); err == nil {
+			p.nets = append(p.nets, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return p
+}
+
+func (p *proxyTrust) trusts(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, n := range p.nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address rate limits and logs attribute r to.
+func (p *proxyTrust) clientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !p.anyPeer && !p.trusts(peer) {
+		return peer
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(h, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		client = hops[i]
+		if !p.trusts(client) {
+			break
+		}
+	}
+	return client
+}
+
+func (s *Server) clientIP(r *http.Request) string {
+	return s.proxies.clientIP(r)
+}
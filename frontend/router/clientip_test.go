@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cases := map[string]struct {
+		trusted []string
+		peer    string
+		xff     []string
+		want    string
+	}{
+		"no proxies ignores header": {
+			peer: "203.0.113.7:5000", xff: []string{"1.2.3.4"}, want: "203.0.113.7",
+		},
+		"untrusted peer ignores header": {
+			trusted: []string{"10.0.0.0/8"},
+			peer:    "203.0.113.7:5000", xff: []string{"1.2.3.4"}, want: "203.0.113.7",
+		},
+		"trusted peer": {
+			trusted: []string{"10.0.0.0/8"},
+			peer:    "10.0.0.2:5000", xff: []string{"198.51.100.9"}, want: "198.51.100.9",
+		},
+		"spoofed leftmost entry": {
+			trusted: []string{"10.0.0.0/8"},
+			peer:    "10.0.0.2:5000", xff: []string{"1.2.3.4, 198.51.100.9"}, want: "198.51.100.9",
+		},
+		"chain of trusted proxies": {
+			trusted: []string{"10.0.0.0/8", "192.0.2.1"},
+			peer:    "10.0.0.2:5000", xff: []string{"1.2.3.4, 198.51.100.9, 192.0.2.1", "10.1.1.1"}, want: "198.51.100.9",
+		},
+		"all hops trusted": {
+			trusted: []string{"10.0.0.0/8"},
+			peer:    "10.0.0.2:5000", xff: []string{"10.0.0.3"}, want: "10.0.0.3",
+		},
+		"trusted peer without header": {
+			trusted: []string{"10.0.0.0/8"},
+			peer:    "10.0.0.2:5000", want: "10.0.0.2",
+		},
+		"any peer": {
+			trusted: []string{"*"},
+			peer:    "203.0.113.7:5000", xff: []string{"1.2.3.4, 198.51.100.9"}, want: "198.51.100.9",
+		},
+		"ipv6 peer": {
+			trusted: []string{"fd00::/8"},
+			peer:    "[fd00::1]:5000", xff: []string{"2001:db8::5"}, want: "2001:db8::5",
+		},
+	}
+	for name, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.peer
+		for _, h := range c.xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		if got := newProxyTrust(c.trusted).clientIP(r); got != c.want {
+			t.Errorf("%s: got %q, want %q", name, got, c.want)
+		}
+	}
+}
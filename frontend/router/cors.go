@@ -0,0 +1,126 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsMethods are the methods probed against the mux to answer a
+// preflight with just the methods the requested route accepts.
+var corsMethods = []string{"GET", "POST", "PATCH", "DELETE"}
+
+const (
+	corsAllowHeaders  = "Content-Type, Authorization, X-Request-ID"
+	corsExposeHeaders = "Content-Disposition, Retry-After, X-Request-ID"
+)
+
+// corsPolicy decides which browser origins may call the API. Entries are
+// exact origins ("https://forge.vaelia.app"), subdomain wildcards
+// ("https://*.vaelia.app", which does not match the bare domain) or "*".
+// Listed origins may send credentials; "*" never does.
+type corsPolicy struct {
+	any      bool
+	exact    map[string]bool
+	suffixes []originSuffix
+	maxAge   string
+}
+
+type originSuffix struct {
+	scheme, suffix, port string
+}
+
+func newCORSPolicy(origins []string, maxAge time.Duration) *corsPolicy {
+	p := &corsPolicy{exact: map[string]bool{}, maxAge: strconv.Itoa(int(maxAge.Seconds()))}
+	for _, o := range origins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		if o == "*" {
+			p.any = true
+			continue
+		}
+		if scheme, host, ok := strings.Cut(o, "://*."); ok {
+			u, err := url.Parse(scheme + "://" + host)
+			if err == nil {
+				p.suffixes = append(p.suffixes, originSuffix{scheme: scheme, suffix: "." + u.Hostname(), port: u.Port()})
+			}
+			continue
+		}
+		p.exact[o] = true
+	}
+	return p
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, s := range p.suffixes {
+		if u.Scheme == s.scheme && u.Port() == s.port && strings.HasSuffix(u.Hostname(), s.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle sets the CORS response headers for r. It returns false when it
+// has answered a preflight request itself.
+func (p *corsPolicy) handle(w http.ResponseWriter, r *http.Request, mux *http.ServeMux) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+	}
+
+	switch {
+	case origin == "":
+		return true
+	case p.allowed(origin):
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	case p.any:
+		h.Set("Access-Control-Allow-Origin", "*")
+	default:
+		if preflight {
+			httpError(w, r, "Origin not allowed", 403)
+			return false
+		}
+		// The browser blocks the response without the CORS headers.
+		return true
+	}
+	h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+	if !preflight {
+		return true
+	}
+
+	methods := routeMethods(mux, r)
+	if len(methods) == 0 {
+		http.NotFound(w, r)
+		return false
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+	h.Set("Access-Control-Max-Age", p.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+	return false
+}
+
+// routeMethods lists the methods the mux has a route for at r's path.
+func routeMethods(mux *http.ServeMux, r *http.Request) []string {
+	var methods []string
+	for _, m := range corsMethods {
+		probe := &http.Request{Method: m, URL: r.URL, Host: r.Host, Header: http.Header{}}
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
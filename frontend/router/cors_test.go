@@ -0,0 +1,122 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	cases := map[string]struct {
+		origins     []string
+		origin      string
+		allowOrigin string
+		credentials bool
+	}{
+		"exact": {
+			origins: []string{"https://forge.vaelia.app"},
+			origin:  "https://forge.vaelia.app", allowOrigin: "https://forge.vaelia.app", credentials: true,
+		},
+		"exact with trailing slash and case": {
+			origins: []string{"https://Forge.vaelia.app/"},
+			origin:  "https://forge.vaelia.app", allowOrigin: "https://forge.vaelia.app", credentials: true,
+		},
+		"exact other origin": {
+			origins: []string{"https://forge.vaelia.app"},
+			origin:  "https://evil.example",
+		},
+		"exact other scheme": {
+			origins: []string{"https://forge.vaelia.app"},
+			origin:  "http://forge.vaelia.app",
+		},
+		"wildcard subdomain": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://app.example.com", allowOrigin: "https://app.example.com", credentials: true,
+		},
+		"wildcard nested subdomain": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://a.b.example.com", allowOrigin: "https://a.b.example.com", credentials: true,
+		},
+		"wildcard skips apex": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://example.com",
+		},
+		"wildcard skips lookalike": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://evilexample.com",
+		},
+		"wildcard other scheme": {
+			origins: []string{"https://*.example.com"},
+			origin:  "http://app.example.com",
+		},
+		"wildcard other port": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://app.example.com:8443",
+		},
+		"any origin without credentials": {
+			origins: []string{"*"},
+			origin:  "https://anyone.example", allowOrigin: "*",
+		},
+		"listed origin keeps credentials alongside any": {
+			origins: []string{"*", "https://forge.vaelia.app"},
+			origin:  "https://forge.vaelia.app", allowOrigin: "https://forge.vaelia.app", credentials: true,
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/ping", func(http.ResponseWriter, *http.Request) {})
+	for name, c := range cases {
+		p := newCORSPolicy(c.origins, time.Minute)
+		r := httptest.NewRequest("GET", "/api/ping", nil)
+		r.Header.Set("Origin", c.origin)
+		w := httptest.NewRecorder()
+		if !p.handle(w, r, mux) {
+			t.Errorf("%s: a simple request was answered by the CORS handler", name)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != c.allowOrigin {
+			t.Errorf("%s: Allow-Origin = %q, want %q", name, got, c.allowOrigin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != c.credentials {
+			t.Errorf("%s: credentials allowed = %v, want %v", name, got, c.credentials)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/history", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("POST /api/generate", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("PATCH /api/orgs/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("DELETE /api/orgs/{id}", func(http.ResponseWriter, *http.Request) {})
+	p := newCORSPolicy([]string{"https://forge.vaelia.app"}, 10*time.Minute)
+
+	cases := map[string]struct {
+		origin, path string
+		code         int
+		methods      string
+	}{
+		"get-only route":    {origin: "https://forge.vaelia.app", path: "/api/history", code: 204, methods: "GET"},
+		"post route":        {origin: "https://forge.vaelia.app", path: "/api/generate", code: 204, methods: "POST"},
+		"several methods":   {origin: "https://forge.vaelia.app", path: "/api/orgs/1", code: 204, methods: "PATCH, DELETE"},
+		"unknown route":     {origin: "https://forge.vaelia.app", path: "/api/nope", code: 404},
+		"disallowed origin": {origin: "https://evil.example", path: "/api/history", code: 403},
+	}
+	for name, c := range cases {
+		r := httptest.NewRequest("OPTIONS", c.path, nil)
+		r.Header.Set("Origin", c.origin)
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		if p.handle(w, r, mux) {
+			t.Errorf("%s: preflight was passed on to the mux", name)
+		}
+		if w.Code != c.code {
+			t.Errorf("%s: status %d, want %d", name, w.Code, c.code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != c.methods {
+			t.Errorf("%s: Allow-Methods = %q, want %q", name, got, c.methods)
+		}
+		if c.code == 204 && w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s: Max-Age = %q, want 600", name, w.Header().Get("Access-Control-Max-Age"))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+func (s *Server) handleGetCredits(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	var balance int
+	s.pool.QueryRow(r.Context(), "SELECT credit_balance FROM users WHERE id = $1::uuid", userID).Scan(&balance)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"credits": balance})
+}
+
+func (s *Server) handleRedeemPromo(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	granted, err := logic.RedeemPromo(r.Context(), s.pool, userID, req.Code)
+	switch {
+	case errors.Is(err, logic.ErrInvalidCode):
+		httpError(w, r, "Unknown promo code", 404)
+		return
+	case errors.Is(err, logic.ErrCodeExpired), errors.Is(err, logic.ErrCodeExhausted):
+		httpError(w, r, "Promo code is no longer valid", 410)
+		return
+	case errors.Is(err, logic.ErrAlreadyRedeemed):
+		httpError(w, r, "Promo code already redeemed", 409)
+		return
+	case err != nil:
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	var balance int
+	s.pool.QueryRow(r.Context(), "SELECT credit_balance FROM users WHERE id = $1::uuid", userID).Scan(&balance)
+	writeJSON(w, 200, map[string]int{"granted": granted, "credits": balance})
+}
+
+func (s *Server) handleGetReferral(w http.ResponseWriter, r *http.Request) {
+	code, err := logic.ReferralCode(r.Context(), s.pool, currentUser(r).ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{
+		"code":  code,
+		"link":  s.appURL() + "/login?ref=" + code,
+		"bonus": logic.ReferralBonus,
+	})
+}
+
+func (s *Server) handleClaimReferral(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	err := logic.ClaimReferral(r.Context(), s.pool, currentUser(r).ID, req.Code)
+	switch {
+	case errors.Is(err, logic.ErrInvalidCode):
+		httpError(w, r, "Unknown referral code", 404)
+	case errors.Is(err, logic.ErrSelfReferral):
+		httpError(w, r, "You cannot refer yourself", 400)
+	case errors.Is(err, logic.ErrAlreadyReferred):
+		httpError(w, r, "Referral can no longer be set", 409)
+	case err != nil:
+		httpError(w, r, "Database error", 500)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,488 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"github.com/ElvanForge/lesson-forge/backend/prompts"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+)
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	countryCode := r.Header.Get("x-vercel-ip-country")
+
+	var req struct {
+		Prompt         string `json:"prompt"`
+		Mode           string `json:"mode"`
+		Grade          string `json:"grade"`
+		Duration       string `json:"duration"`
+		GenerateImages bool   `json:"generateImages"`
+		OrgID          string `json:"orgId"`
+		Locale         string `json:"locale"`
+	}
+	outcome := outcomeInvalidInput
+	defer func() { s.metrics.generations.Inc("generate", modeLabel(req.Mode), outcome) }()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.OrgID != "" && !validID(req.OrgID)) {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+
+	verdict := s.screen(r, "generate",
+		safety.Field{Name: "prompt", Value: &req.Prompt, Max: maxPromptChars, Required: true},
+		safety.Field{Name: "grade", Value: &req.Grade, Max: 50, SingleLine: true},
+		safety.Field{Name: "duration", Value: &req.Duration, Max: 50, SingleLine: true},
+	)
+	if verdict.Blocked() {
+		outcome = outcomeScreened
+		httpError(w, r, "Request blocked: "+verdict.Reason(), 422)
+		return
+	}
+
+	name := prompts.Lesson
+	if req.Mode == "ppt" { name = prompts.PPT }
+	locale := requestLocale(r, req.Locale)
+	tmpl, currentPrompt, err := s.buildPrompt(name, locale, req.Grade, userID, prompts.Data{
+		Topic:    req.Prompt,
+		Grade:    req.Grade,
+		Duration: req.Duration,
+		Locale:   locale,
+	})
+	if err != nil {
+		outcome = outcomePrompt
+		logFor(r).Error("building prompt failed", "err", err)
+		httpError(w, r, "Could not build prompt", 500)
+		return
+	}
+
+	cost := 1
+	if req.Mode == "ppt" { cost = 2 }
+
+	acct := logic.Account{UserID: userID, OrgID: req.OrgID}
+	if err := s.debit(r.Context(), acct, cost, logic.ReasonGenerate); err != nil {
+		outcome = debitFailed(w, r, err)
+		return
+	}
+	s.metrics.creditsDebited.Add(float64(cost), "generate")
+
+	provider := s.ai.Provider(countryCode)
+	content, usage, moderation, err := s.generateModerated(r, provider, moderatedRequest{
+		kind: "generate", mode: req.Mode, template: tmpl.ID(),
+		prompt: currentPrompt, images: req.GenerateImages,
+		grade: req.Grade, locale: locale,
+	})
+	if err != nil {
+		outcome = outcomeAI
+		s.refund(r, acct, cost)
+		httpError(w, r, "AI error", 500)
+		return
+	}
+	if !moderation.Passed {
+		outcome = outcomeModeration
+		s.recordBlockedOutput(r, "generate", moderation, map[string]string{
+			"prompt": req.Prompt, "grade": req.Grade, "mode": req.Mode, "content": content,
+		})
+		s.refund(r, acct, cost)
+		httpError(w, r, "The generated content didn't pass our age-appropriateness check. Your credits were refunded; try rephrasing the topic.", 422)
+		return
+	}
+	level := s.adjustReadability(r, provider, req.Mode, req.Grade, locale, &content, &usage, &moderation)
+
+	file, err := s.render(r.Context(), logic.DefaultFormat(req.Mode), userID, content)
+	if err != nil {
+		outcome = outcomeRender
+		logFor(r).Error("render failed", "err", err, "mode", req.Mode)
+		s.refund(r, acct, cost)
+		httpError(w, r, "Render failed", 500)
+		return
+	}
+
+	key := fmt.Sprintf("%s/%d_%s", userID, time.Now().Unix(), file.Name)
+	if err := s.put(r.Context(), key, file); err != nil {
+		outcome = outcomeUpload
+		logFor(r).Error("upload failed", "err", err, "key", key)
+		s.refund(r, acct, cost)
+		httpError(w, r, "Storage upload failed", 500)
+		return
+	}
+
+	gen := logic.Generation{
+		UserID:    userID,
+		Prompt:    req.Prompt,
+		Mode:      req.Mode,
+		Grade:     req.Grade,
+		Duration:  req.Duration,
+		FilePath:  key,
+		Content:   content,
+		Structure: logic.ParseContent(req.Mode, content),
+		Usage:     &usage,
+
+		TemplateVersion: tmpl.ID(),
+		Moderation:      &moderation,
+	}
+	if req.OrgID != "" { gen.OrgID = &req.OrgID }
+	if err := s.saveGeneration(r.Context(), &gen); err != nil {
+		outcome = outcomeDB
+		logFor(r).Error("generation insert failed", "err", err, "key", key)
+		s.refund(r, acct, cost)
+		httpError(w, r, "Could not save the generation", 500)
+		return
+	}
+	outcome = outcomeOK
+
+	url, _ := s.store.SignedURL(r.Context(), key, logic.DownloadURLTTL)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id": gen.ID,
+		"file": url,
+		"raw_content": content,
+		"readability": level,
+	})
+}
+
+// handleDownload hands the owner of a generation a short-lived signed link
+// to its file, as JSON or (with ?redirect=1) as a 302.
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !validID(id) {
+		httpError(w, r, "Generation not found", 404)
+		return
+	}
+	key, err := logic.GenerationFile(r.Context(), s.pool, id, currentUser(r).ID)
+	if errors.Is(err, logic.ErrGenerationNotFound) {
+		httpError(w, r, "Generation not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+
+	key, ok := logic.StorageKey(key)
+	if !ok {
+		httpError(w, r, "File not found", 404)
+		return
+	}
+	url, err := s.store.SignedURL(r.Context(), key, logic.DownloadURLTTL)
+	if errors.Is(err, logic.ErrObjectNotFound) {
+		httpError(w, r, "File not found", 404)
+		return
+	}
+	if err != nil {
+		logFor(r).Error("signing download url failed", "err", err)
+		httpError(w, r, "Storage error", 502)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("redirect") == "1" {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{
+		"url":       url,
+		"expiresAt": time.Now().Add(logic.DownloadURLTTL).UTC(),
+	})
+}
+
+// sectionEditCost is charged for rewriting one slide or section, instead
+// of paying for a whole new generation.
+const sectionEditCost = 1
+
+// Input length limits, in characters, enforced by input screening.
+const (
+	maxPromptChars      = 2000
+	maxInstructionChars = 500
+)
+
+// handleEditSection rewrites one slide/section of a stored generation
+// following the user's instruction, keeps the rest intact and re-renders
+// the file.
+func (s *Server) handleEditSection(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	var req struct {
+		Instruction string `json:"instruction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Instruction) == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	if gen.Content == "" {
+		httpError(w, r, "This generation predates stored content and cannot be edited", 409)
+		return
+	}
+	if len(gen.Structure.Sections) == 0 { gen.Structure = logic.ParseContent(gen.Mode, gen.Content) }
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 || index >= len(gen.Structure.Sections) {
+		httpError(w, r, "Section not found", 404)
+		return
+	}
+
+	verdict := s.screen(r, "edit_section",
+		safety.Field{Name: "instruction", Value: &req.Instruction, Max: maxInstructionChars, Required: true})
+	if verdict.Blocked() {
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcomeScreened)
+		httpError(w, r, "Request blocked: "+verdict.Reason(), 422)
+		return
+	}
+
+	target := gen.Structure.Sections[index]
+	name := prompts.LessonSection
+	if gen.Mode == "ppt" { name = prompts.PPTSlide }
+	locale := requestLocale(r, "")
+	tmpl, currentPrompt, err := s.buildPrompt(name, locale, gen.Grade, userID, prompts.Data{
+		Topic:       gen.Prompt,
+		Grade:       gen.Grade,
+		Duration:    gen.Duration,
+		Locale:      locale,
+		Index:       index + 1,
+		Title:       target.Title,
+		Section:     target.Title + "\n" + strings.Join(target.Lines, "\n"),
+		Instruction: req.Instruction,
+		Content:     gen.Content,
+	})
+	if err != nil {
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcomePrompt)
+		logFor(r).Error("building prompt failed", "err", err)
+		httpError(w, r, "Could not build prompt", 500)
+		return
+	}
+
+	acct := logic.Account{UserID: userID}
+	if gen.OrgID != nil { acct.OrgID = *gen.OrgID }
+	if err := s.debit(r.Context(), acct, sectionEditCost, logic.ReasonEditSection); err != nil {
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), debitFailed(w, r, err))
+		return
+	}
+	s.metrics.creditsDebited.Add(sectionEditCost, "edit_section")
+
+	provider := s.ai.Provider(r.Header.Get("x-vercel-ip-country"))
+	text, usage, moderation, err := s.generateModerated(r, provider, moderatedRequest{
+		kind: "edit_section", mode: gen.Mode, template: tmpl.ID(),
+		prompt: currentPrompt,
+		grade:  gen.Grade, locale: locale,
+	})
+	section, ok := logic.ParseSection(gen.Mode, text)
+	if err == nil && !ok {
+		err = errors.New("AI returned no usable section")
+	}
+	if err != nil {
+		logFor(r).Error("section edit failed", "err", err, "generation_id", gen.ID)
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcomeAI)
+		s.refund(r, acct, sectionEditCost)
+		httpError(w, r, "AI error", 500)
+		return
+	}
+	if !moderation.Passed {
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcomeModeration)
+		s.recordBlockedOutput(r, "edit_section", moderation, map[string]string{
+			"generationId": gen.ID, "instruction": req.Instruction, "grade": gen.Grade, "content": text,
+		})
+		s.refund(r, acct, sectionEditCost)
+		httpError(w, r, "The rewritten section didn't pass our age-appropriateness check. Your credit was refunded; try a different instruction.", 422)
+		return
+	}
+	if gen.Mode != "ppt" && target.Title != "" { section.Title = target.Title }
+	gen.Structure.Sections[index] = section
+	gen.Content = gen.Structure.Markdown()
+	gen.Usage = &usage
+	gen.TemplateVersion = tmpl.ID()
+	gen.Moderation = &moderation
+
+	outcome := outcomeRender
+	file, err := s.render(r.Context(), logic.DefaultFormat(gen.Mode), userID, gen.Content)
+	if err == nil {
+		outcome = outcomeUpload
+		gen.FilePath = fmt.Sprintf("%s/%d_%s", userID, time.Now().Unix(), file.Name)
+		err = s.put(r.Context(), gen.FilePath, file)
+	}
+	if err == nil {
+		outcome = outcomeDB
+		note := fmt.Sprintf("Edited section %d: %s", index+1, req.Instruction)
+		gen.Version, err = s.saveVersion(r.Context(), gen, note)
+	}
+	if err != nil {
+		s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcome)
+		logFor(r).Error("saving edited section failed", "err", err, "generation_id", gen.ID)
+		s.refund(r, acct, sectionEditCost)
+		httpError(w, r, "Could not save the edited file", 500)
+		return
+	}
+
+	s.metrics.generations.Inc("edit_section", modeLabel(gen.Mode), outcomeOK)
+	url, _ := s.store.SignedURL(r.Context(), gen.FilePath, logic.DownloadURLTTL)
+	writeJSON(w, 200, map[string]interface{}{
+		"id":          gen.ID,
+		"version":     gen.Version,
+		"file":        url,
+		"section":     section,
+		"raw_content": gen.Content,
+		"readability": s.measure(gen.Content, gen.Grade, locale),
+	})
+}
+
+func (s *Server) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	versions, err := logic.ListVersions(r.Context(), s.pool, gen.ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"current": gen.Version, "versions": versions})
+}
+
+// handleRestoreVersion makes an old version current again by saving it as
+// a new version, so history is never rewritten.
+func (s *Server) handleRestoreVersion(w http.ResponseWriter, r *http.Request) {
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	number, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		httpError(w, r, "Version not found", 404)
+		return
+	}
+	old, err := logic.GetVersion(r.Context(), s.pool, gen.ID, number)
+	if errors.Is(err, logic.ErrVersionNotFound) {
+		httpError(w, r, "Version not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+
+	gen.Content, gen.FilePath, gen.Moderation = old.Content, old.FilePath, old.Moderation
+	gen.Structure = logic.ParseContent(gen.Mode, old.Content)
+	if old.Structure != nil { gen.Structure = *old.Structure }
+	gen.Version, err = logic.SaveVersion(r.Context(), s.pool, gen, fmt.Sprintf("Restored version %d", number))
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"id": gen.ID, "version": gen.Version, "raw_content": gen.Content})
+}
+
+// handleDiffVersions compares two versions section by section:
+// GET /api/generations/{id}/diff?from=1&to=3 (to defaults to current).
+func (s *Server) handleDiffVersions(w http.ResponseWriter, r *http.Request) {
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	to := gen.Version
+	var errTo error
+	if q := r.URL.Query().Get("to"); q != "" {
+		to, errTo = strconv.Atoi(q)
+	}
+	if errFrom != nil || errTo != nil {
+		httpError(w, r, "from and to must be version numbers", 400)
+		return
+	}
+
+	var docs [2]logic.Document
+	for i, n := range []int{from, to} {
+		v, err := logic.GetVersion(r.Context(), s.pool, gen.ID, n)
+		if errors.Is(err, logic.ErrVersionNotFound) {
+			httpError(w, r, fmt.Sprintf("Version %d not found", n), 404)
+			return
+		}
+		if err != nil {
+			httpError(w, r, "Database error", 500)
+			return
+		}
+		docs[i] = logic.ParseContent(gen.Mode, v.Content)
+		if v.Structure != nil { docs[i] = *v.Structure }
+	}
+	writeJSON(w, 200, map[string]interface{}{
+		"from":     from,
+		"to":       to,
+		"sections": logic.DiffDocuments(docs[0], docs[1]),
+	})
+}
+
+// ownedGeneration loads the generation in the {id} path segment for the
+// caller, writing a 404 if it isn't theirs.
+func (s *Server) ownedGeneration(w http.ResponseWriter, r *http.Request) (logic.Generation, bool) {
+	id := r.PathValue("id")
+	if !validID(id) {
+		httpError(w, r, "Generation not found", 404)
+		return logic.Generation{}, false
+	}
+	gen, err := logic.GetGeneration(r.Context(), s.pool, id, currentUser(r).ID)
+	if errors.Is(err, logic.ErrGenerationNotFound) {
+		httpError(w, r, "Generation not found", 404)
+		return gen, false
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return gen, false
+	}
+	return gen, true
+}
+
+// handleRender re-renders a stored generation in another format without
+// calling the AI or charging credits, and streams the file back.
+func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	if gen.Content == "" {
+		httpError(w, r, "This generation predates stored content and cannot be re-rendered", 409)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" { format = logic.DefaultFormat(gen.Mode) }
+	file, err := s.render(r.Context(), format, userID, gen.Content)
+	if errors.Is(err, logic.ErrUnknownFormat) {
+		httpError(w, r, "format must be pdf, pptx, docx or md", 400)
+		return
+	}
+	if err != nil {
+		logFor(r).Error("render failed", "err", err, "format", format)
+		httpError(w, r, "Render failed", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file.Name))
+	w.Write(file.Data)
+}
+
+// buildPrompt renders the template for name that best fits the request.
+// userID keeps a user on one side of a template A/B test.
+func (s *Server) buildPrompt(name, locale, grade, userID string, d prompts.Data) (*prompts.Template, string, error) {
+	tmpl, err := s.prompts.Select(name, locale, grade, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	prompt, err := tmpl.Execute(d)
+	return tmpl, prompt, err
+}
+
+// requestLocale is the locale the client asked for, or else the first
+// language in Accept-Language.
+func requestLocale(r *http.Request, explicit string) string {
+	if l := prompts.NormalizeLocale(explicit); l != "" {
+		return l
+	}
+	return prompts.NormalizeLocale(r.Header.Get("Accept-Language"))
+}
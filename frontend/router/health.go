@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+const (
+	// checkTimeout bounds each dependency check so a hung backend shows
+	// up as down instead of stalling the probe.
+	checkTimeout = 3 * time.Second
+	// aiCheckTTL caches provider checks; load balancers probe every few
+	// seconds and the providers rate limit model listing like any call.
+	aiCheckTTL = time.Minute
+)
+
+// checkResult is one dependency's entry in the /readyz response.
+type checkResult struct {
+	Status    string `json:"status"` // ok or down
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// aiHealth remembers the last check of each AI provider.
+type aiHealth struct {
+	mu      sync.Mutex
+	results map[string]checkResult
+	checked map[string]time.Time
+}
+
+// handleHealthz is the liveness probe: the process is up and serving.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, map[string]string{"status": "ok"})
+}
+
+// handleReadyz checks every dependency and reports each one. It answers
+// 503 only when a critical one (database, storage) is down; an AI outage
+// leaves the instance "degraded" but in rotation, since every instance
+// would be affected alike and most routes still work.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(context.Context) error{
+		"database": s.pool.Ping,
+		"storage":  s.store.Check,
+	}
+	var mu sync.Mutex
+	results := map[string]checkResult{}
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := runCheck(r, name, check)
+			res.Critical = true
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}()
+	}
+	for _, p := range s.ai.Providers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := s.checkAI(r, p)
+			mu.Lock()
+			results["ai."+p.Name()] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status, code := "ok", 200
+	for _, res := range results {
+		if res.Status == "ok" {
+			continue
+		}
+		if res.Critical {
+			status, code = "unavailable", 503
+			break
+		}
+		status = "degraded"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": results})
+}
+
+// checkAI returns the cached result for p, checking again once it is
+// older than aiCheckTTL.
+func (s *Server) checkAI(r *http.Request, p logic.AIProvider) checkResult {
+	h := &s.aiHealth
+	h.mu.Lock()
+	if at, ok := h.checked[p.Name()]; ok && time.Since(at) < aiCheckTTL {
+		res := h.results[p.Name()]
+		h.mu.Unlock()
+		return res
+	}
+	h.mu.Unlock()
+
+	res := runCheck(r, "ai."+p.Name(), p.Check)
+	h.mu.Lock()
+	if h.results == nil {
+		h.results, h.checked = map[string]checkResult{}, map[string]time.Time{}
+	}
+	h.results[p.Name()], h.checked[p.Name()] = res, time.Now()
+	h.mu.Unlock()
+	return res
+}
+
+// runCheck times one check. Failures are logged in full but reported only
+// as "timeout" or "unreachable", since the probe is unauthenticated and
+// driver errors name hosts and users.
+func runCheck(r *http.Request, name string, check func(context.Context) error) checkResult {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	res := checkResult{Status: "ok", LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		logFor(r).Warn("dependency check failed", "check", name, "err", err)
+		res.Status, res.Error = "down", "unreachable"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			res.Error = "timeout"
+		}
+	}
+	return res
+}
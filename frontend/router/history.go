@@ -0,0 +1,191 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+// handleListGenerations serves paginated, searchable history:
+// GET /api/generations?q=&mode=&grade=&tag=&folder=&from=&to=&page=&pageSize=
+// where from/to are dates (YYYY-MM-DD) and to is inclusive.
+func (s *Server) handleListGenerations(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := logic.HistoryFilter{
+		Query:    q.Get("q"),
+		Mode:     q.Get("mode"),
+		Grade:    q.Get("grade"),
+		Tag:      q.Get("tag"),
+		FolderID: q.Get("folder"),
+	}
+	var err error
+	if v := q.Get("from"); v != "" && err == nil {
+		f.From, err = time.Parse(time.DateOnly, v)
+	}
+	if v := q.Get("to"); v != "" && err == nil {
+		f.To, err = time.Parse(time.DateOnly, v)
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+	if v := q.Get("page"); v != "" && err == nil {
+		f.Page, err = strconv.Atoi(v)
+	}
+	if v := q.Get("pageSize"); v != "" && err == nil {
+		f.PageSize, err = strconv.Atoi(v)
+	}
+	if err != nil || (f.FolderID != "" && !validID(f.FolderID)) {
+		httpError(w, r, "Invalid filter", 400)
+		return
+	}
+
+	f = f.Normalized()
+	items, total, err := logic.SearchHistory(r.Context(), s.pool, currentUser(r).ID, f)
+	if err != nil {
+		logFor(r).Error("history search failed", "err", err)
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{
+		"items":    items,
+		"total":    total,
+		"page":     f.Page,
+		"pageSize": f.PageSize,
+	})
+}
+
+// handleUpdateGeneration changes a generation's tags and/or folder. A null
+// folderId takes it out of its folder.
+func (s *Server) handleUpdateGeneration(w http.ResponseWriter, r *http.Request) {
+	userID, id := currentUser(r).ID, r.PathValue("id")
+	if !validID(id) {
+		httpError(w, r, "Generation not found", 404)
+		return
+	}
+	var req struct {
+		Tags     *[]string       `json:"tags"`
+		FolderID json.RawMessage `json:"folderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	var err error
+	if req.Tags != nil {
+		var tags []string
+		if tags, err = logic.NormalizeTags(*req.Tags); err != nil {
+			httpError(w, r, "Up to 20 tags of at most 32 characters", 400)
+			return
+		}
+		err = logic.SetTags(r.Context(), s.pool, id, userID, tags)
+	}
+	if req.FolderID != nil && err == nil {
+		var folderID *string
+		if json.Unmarshal(req.FolderID, &folderID) != nil || (folderID != nil && !validID(*folderID)) {
+			httpError(w, r, "Invalid folderId", 400)
+			return
+		}
+		if folderID == nil { folderID = new(string) }
+		err = logic.MoveToFolder(r.Context(), s.pool, id, userID, *folderID)
+	}
+	switch {
+	case errors.Is(err, logic.ErrGenerationNotFound):
+		httpError(w, r, "Generation not found", 404)
+	case errors.Is(err, logic.ErrFolderNotFound):
+		httpError(w, r, "Folder not found", 404)
+	case err != nil:
+		httpError(w, r, "Database error", 500)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	counts, err := logic.TagCounts(r.Context(), s.pool, currentUser(r).ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"tags": counts})
+}
+
+func (s *Server) handleListFolders(w http.ResponseWriter, r *http.Request) {
+	folders, err := logic.ListFolders(r.Context(), s.pool, currentUser(r).ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"folders": folders})
+}
+
+func (s *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeFolderName(w, r)
+	if !ok {
+		return
+	}
+	f, err := logic.CreateFolder(r.Context(), s.pool, currentUser(r).ID, name)
+	if errors.Is(err, logic.ErrFolderExists) {
+		httpError(w, r, "A folder with that name already exists", 409)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 201, f)
+}
+
+func (s *Server) handleRenameFolder(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeFolderName(w, r)
+	if !ok {
+		return
+	}
+	folderID := r.PathValue("folderID")
+	if !validID(folderID) {
+		httpError(w, r, "Folder not found", 404)
+		return
+	}
+	err := logic.RenameFolder(r.Context(), s.pool, folderID, currentUser(r).ID, name)
+	switch {
+	case errors.Is(err, logic.ErrFolderNotFound):
+		httpError(w, r, "Folder not found", 404)
+	case errors.Is(err, logic.ErrFolderExists):
+		httpError(w, r, "A folder with that name already exists", 409)
+	case err != nil:
+		httpError(w, r, "Database error", 500)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleDeleteFolder(w http.ResponseWriter, r *http.Request) {
+	folderID := r.PathValue("folderID")
+	if !validID(folderID) {
+		httpError(w, r, "Folder not found", 404)
+		return
+	}
+	err := logic.DeleteFolder(r.Context(), s.pool, folderID, currentUser(r).ID)
+	if errors.Is(err, logic.ErrFolderNotFound) {
+		httpError(w, r, "Folder not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeFolderName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" || len(req.Name) > 100 {
+		httpError(w, r, "Invalid request", 400)
+		return "", false
+	}
+	return strings.TrimSpace(req.Name), true
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := logic.ListAPIKeys(r.Context(), s.pool, currentUser(r).ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"keys": keys})
+}
+
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	if !logic.ValidScopes(req.Scopes) {
+		httpError(w, r, "Unknown scope", 400)
+		return
+	}
+	key, plain, err := logic.CreateAPIKey(r.Context(), s.pool, currentUser(r).ID, strings.TrimSpace(req.Name), req.Scopes)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	// The plaintext key is only ever shown in this response.
+	writeJSON(w, 201, map[string]interface{}{"key": plain, "apiKey": key})
+}
+
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID := r.PathValue("keyID")
+	if !validID(keyID) {
+		httpError(w, r, "API key not found", 404)
+		return
+	}
+	err := logic.RevokeAPIKey(r.Context(), s.pool, currentUser(r).ID, keyID)
+	if errors.Is(err, logic.ErrKeyNotFound) {
+		httpError(w, r, "API key not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetupLogging makes JSON on stdout the default slog output.
+func SetupLogging(level slog.Level) {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+}
+
+type requestInfoKey struct{}
+
+// requestInfo travels in the request context so the access log, written
+// after the handler returns, can see who the auth middleware let in.
+type requestInfo struct {
+	id     string
+	userID string
+}
+
+func requestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+func setRequestUser(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// incomingRequestID accepts an X-Request-ID set by a proxy in front of us,
+// as long as it is short and plain enough to echo back and log.
+func incomingRequestID(r *http.Request) string {
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || len(id) > 64 {
+		return ""
+	}
+	for _, c := range id {
+		if !(c == '-' || c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return ""
+		}
+	}
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withRequestLog tags the request with an ID, returns it in X-Request-ID
+// and logs and counts each request once it is done.
+func (s *Server) withRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{id: incomingRequestID(r)}
+		if info.id == "" {
+			info.id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", info.id)
+		ctx, span := startRequestSpan(r, s.clientIP(r))
+		span.SetAttributes(attribute.String("request.id", info.id))
+		r = r.WithContext(context.WithValue(ctx, requestInfoKey{}, info))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: 200}
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(start)
+		endRequestSpan(span, r, rec.status)
+		s.metrics.observeRequest(r, rec.status, elapsed)
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case probeRoutes[r.Pattern]:
+			// Health probes arrive every few seconds; only log them
+			// at debug level unless they fail.
+			level = slog.LevelDebug
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("request_id", info.id),
+			slog.String("trace_id", traceID(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Int64("duration_ms", elapsed.Milliseconds()),
+			slog.String("user_id", info.userID),
+			slog.String("ip", s.clientIP(r)),
+		)
+	})
+}
+
+var probeRoutes = map[string]bool{
+	"GET /healthz": true, "GET /readyz": true,
+	"GET /api/healthz": true, "GET /api/readyz": true,
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// logFor returns the default logger tagged with the request's ID, its
+// trace when tracing is on and, once authenticated, its user.
+func logFor(r *http.Request) *slog.Logger {
+	l := slog.Default().With("request_id", requestID(r.Context()))
+	if id := traceID(r.Context()); id != "" {
+		l = l.With("trace_id", id)
+	}
+	if u, ok := logic.UserFrom(r.Context()); ok {
+		l = l.With("user_id", u.ID)
+	}
+	return l
+}
+
+// httpError is http.Error with the request ID appended, so a user can
+// quote it to support and we can find the matching log lines.
+func httpError(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	if id := requestID(r.Context()); id != "" {
+		msg += " (request ID: " + id + ")"
+	}
+	http.Error(w, msg, code)
+}
+
+// logGeneration records one AI call with what we need to correlate a
+// failed lesson: prompt template, provider, model, latency, token usage
+// and cost.
+func (s *Server) logGeneration(r *http.Request, kind, mode, template string, u logic.Usage, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	s.metrics.aiDuration.Observe(float64(u.LatencyMS)/1000, u.Provider, kind, outcome)
+	s.metrics.tokens.Add(float64(u.InputTokens), u.Provider, "input")
+	s.metrics.tokens.Add(float64(u.OutputTokens), u.Provider, "output")
+	s.metrics.aiCost.Add(u.CostUSD, u.Provider)
+
+	attrs := []any{
+		"kind", kind,
+		"mode", mode,
+		"template", template,
+		"provider", u.Provider,
+		"model", u.Model,
+		"latency_ms", u.LatencyMS,
+		"input_tokens", u.InputTokens,
+		"output_tokens", u.OutputTokens,
+		"cost_usd", u.CostUSD,
+	}
+	if err != nil {
+		logFor(r).Error("generation failed", append(attrs, "err", err)...)
+		return
+	}
+	logFor(r).Info("generation", attrs...)
+}
+
+// debitFailed answers a failed debit and returns the metrics outcome for it.
+func debitFailed(w http.ResponseWriter, r *http.Request, err error) string {
+	switch {
+	case errors.Is(err, logic.ErrNotOrgMember):
+		httpError(w, r, "Not a member of this organization", 403)
+		return outcomeForbidden
+	case errors.Is(err, logic.ErrSpendLimitReached):
+		httpError(w, r, "Organization spending limit reached", 402)
+		return outcomeSpendLimit
+	default:
+		httpError(w, r, "Insufficient credits or DB error", 402)
+		return outcomeCredits
+	}
+}
+
+// refund returns credits after a failed generation, logging if even that
+// fails so the user can be made whole by hand.
+func (s *Server) refund(r *http.Request, acct logic.Account, amount int) {
+	if err := logic.GrantCredits(r.Context(), s.pool, acct, amount, logic.ReasonRefund); err != nil {
+		logFor(r).Error("refund failed", "err", err, "org_id", acct.OrgID, "amount", amount)
+		return
+	}
+	s.metrics.creditsRefunded.Add(float64(amount))
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Generation outcomes, used as the outcome label of
+// lessonforge_generations_total.
+const (
+	outcomeOK           = "ok"
+	outcomeCredits      = "insufficient_credits"
+	outcomeForbidden    = "forbidden"
+	outcomeSpendLimit   = "spend_limit"
+	outcomeAI           = "ai_error"
+	outcomeRender       = "render_error"
+	outcomeUpload       = "upload_error"
+	outcomeDB           = "db_error"
+	outcomeInvalidInput = "invalid_request"
+	outcomePrompt       = "prompt_error"
+	outcomeScreened     = "input_rejected"
+	outcomeModeration   = "moderation_blocked"
+)
+
+type serverMetrics struct {
+	registry *metrics.Registry
+
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	aiDuration      *metrics.HistogramVec
+	renderDuration  *metrics.HistogramVec
+	uploadDuration  *metrics.HistogramVec
+	generations     *metrics.CounterVec
+	creditsDebited  *metrics.CounterVec
+	creditsRefunded *metrics.CounterVec
+	tokens          *metrics.CounterVec
+	aiCost          *metrics.CounterVec
+	screened        *metrics.CounterVec
+	moderation      *metrics.CounterVec
+	rewrites        *metrics.CounterVec
+}
+
+func newServerMetrics(pool *pgxpool.Pool) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		requests: r.NewCounterVec("lessonforge_http_requests_total",
+			"HTTP requests by route pattern and status code.", "route", "code"),
+		requestDuration: r.NewHistogramVec("lessonforge_http_request_duration_seconds",
+			"HTTP request latency by route pattern.", metrics.DefBuckets, "route"),
+		aiDuration: r.NewHistogramVec("lessonforge_ai_request_duration_seconds",
+			"Latency of AI provider calls.", metrics.AIBuckets, "provider", "kind", "outcome"),
+		renderDuration: r.NewHistogramVec("lessonforge_render_duration_seconds",
+			"Time to render a lesson into a file.", metrics.DefBuckets, "format"),
+		uploadDuration: r.NewHistogramVec("lessonforge_upload_duration_seconds",
+			"Time to upload a rendered file to storage.", metrics.DefBuckets, "outcome"),
+		generations: r.NewCounterVec("lessonforge_generations_total",
+			"Generation requests by mode and outcome (ok or the error class).", "kind", "mode", "outcome"),
+		creditsDebited: r.NewCounterVec("lessonforge_credits_debited_total",
+			"Credits charged for generations.", "kind"),
+		creditsRefunded: r.NewCounterVec("lessonforge_credits_refunded_total",
+			"Credits returned after failed generations."),
+		tokens: r.NewCounterVec("lessonforge_ai_tokens_total",
+			"Tokens reported by AI providers.", "provider", "direction"),
+		aiCost: r.NewCounterVec("lessonforge_ai_cost_usd_total",
+			"Estimated AI spend at list prices.", "provider"),
+		screened: r.NewCounterVec("lessonforge_input_screened_total",
+			"Requests the input screening stage found problems with, by action taken.", "kind", "action"),
+		moderation: r.NewCounterVec("lessonforge_output_moderation_total",
+			"Moderation checks of generated content, by verdict (passed or the failing rule).", "kind", "verdict"),
+		rewrites: r.NewCounterVec("lessonforge_readability_rewrites_total",
+			"Readability rewrites by direction and whether the rewrite was kept.", "adjust", "outcome"),
+	}
+	if pool != nil {
+		registerPoolMetrics(r, pool)
+	}
+	return m
+}
+
+// registerPoolMetrics exposes pgxpool's own statistics, read at scrape time.
+func registerPoolMetrics(r *metrics.Registry, pool *pgxpool.Pool) {
+	gauge := func(name, help string, fn func(*pgxpool.Stat) float64) {
+		r.NewGaugeFunc("lessonforge_db_pool_"+name, help, func() float64 { return fn(pool.Stat()) })
+	}
+	counter := func(name, help string, fn func(*pgxpool.Stat) float64) {
+		r.NewCounterFunc("lessonforge_db_pool_"+name, help, func() float64 { return fn(pool.Stat()) })
+	}
+	gauge("acquired_conns", "Connections currently checked out.", func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) })
+	gauge("idle_conns", "Idle connections in the pool.", func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) })
+	gauge("total_conns", "Open connections, including ones being established.", func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) })
+	gauge("constructing_conns", "Connections being established.", func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) })
+	gauge("max_conns", "Maximum pool size.", func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) })
+	counter("acquires_total", "Successful connection acquisitions.", func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) })
+	counter("acquire_duration_seconds_total", "Total time spent acquiring connections.", func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() })
+	counter("empty_acquires_total", "Acquisitions that had to wait for a connection.", func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) })
+	counter("canceled_acquires_total", "Acquisitions canceled by their context.", func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) })
+	counter("new_conns_total", "Connections opened.", func(s *pgxpool.Stat) float64 { return float64(s.NewConnsCount()) })
+	counter("max_lifetime_destroys_total", "Connections closed for exceeding their max lifetime.", func(s *pgxpool.Stat) float64 { return float64(s.MaxLifetimeDestroyCount()) })
+	counter("max_idle_destroys_total", "Connections closed for being idle too long.", func(s *pgxpool.Stat) float64 { return float64(s.MaxIdleDestroyCount()) })
+}
+
+// observeRequest records a finished request under its route pattern, so
+// /api/generations/{id} is one series rather than one per ID.
+func (m *serverMetrics) observeRequest(r *http.Request, status int, elapsed time.Duration) {
+	route := r.Pattern
+	if route == "" {
+		route = "unmatched"
+	}
+	m.requests.Inc(route, strconv.Itoa(status))
+	m.requestDuration.Observe(elapsed.Seconds(), route)
+}
+
+// modeLabel keeps the mode label to known values; mode comes from the
+// request body.
+func modeLabel(mode string) string {
+	if mode == "ppt" {
+		return "ppt"
+	}
+	return "lesson"
+}
+
+// handleMetrics serves /metrics. When METRICS_TOKEN is set, scrapers must
+// send it as a bearer token.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if want := s.cfg.MetricsToken; want != "" {
+		got := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+want)) != 1 {
+			httpError(w, r, "Unauthorized", 401)
+			return
+		}
+	}
+	s.metrics.registry.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"github.com/ElvanForge/lesson-forge/backend/prompts"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+)
+
+// moderatedRequest is what a generation asks of the AI, with what output
+// moderation needs to know about it.
+type moderatedRequest struct {
+	kind, mode, template string
+	prompt               string
+	images               bool
+	grade, locale        string
+}
+
+// generateModerated asks p for content and moderates it, generating again
+// while it fails, up to MODERATION_ATTEMPTS tries in all. usage adds up
+// every call made. err is only set when the AI call itself fails; content
+// that never passed comes back with mod.Passed false.
+func (s *Server) generateModerated(r *http.Request, p logic.AIProvider, req moderatedRequest) (text string, usage logic.Usage, mod safety.Moderation, err error) {
+	for attempt := 1; ; attempt++ {
+		c, err := s.generate(r.Context(), p, req.prompt, req.images)
+		u := logic.UsageOf(p.Name(), c)
+		s.logGeneration(r, req.kind, req.mode, req.template, u, err)
+		usage.Add(u)
+		if err != nil {
+			return "", usage, mod, err
+		}
+
+		mod = safety.Moderate(c.Text, req.grade, req.locale)
+		if mod.Passed && s.cfg.ModerationClassifier {
+			s.classify(r, p, req, c.Text, &mod, &usage)
+		}
+		mod.Attempts = attempt
+		s.metrics.moderation.Inc(req.kind, moderationLabel(mod))
+		if mod.Passed || attempt >= s.cfg.ModerationAttempts {
+			return c.Text, usage, mod, nil
+		}
+		logFor(r).Warn("generated content failed moderation, retrying", "kind", req.kind, "attempt", attempt, "findings", mod.Findings)
+	}
+}
+
+// classify has the moderation model vet text. A failed call lets the
+// content through, since the wordlist checks have already passed it.
+func (s *Server) classify(r *http.Request, p logic.AIProvider, req moderatedRequest, text string, mod *safety.Moderation, usage *logic.Usage) {
+	tmpl, prompt, err := s.buildPrompt(prompts.Moderation, req.locale, req.grade, currentUser(r).ID, prompts.Data{
+		Grade:   req.grade,
+		Locale:  req.locale,
+		Content: text,
+	})
+	if err != nil {
+		logFor(r).Error("building moderation prompt failed", "err", err)
+		mod.Classifier = "error"
+		return
+	}
+	c, err := s.generate(r.Context(), p, prompt, false)
+	u := logic.UsageOf(p.Name(), c)
+	s.logGeneration(r, "moderate", req.mode, tmpl.ID(), u, err)
+	usage.Add(u)
+	if err != nil {
+		mod.Classifier = "error"
+		return
+	}
+	mod.AddClassifierReply(c.Text)
+}
+
+// recordBlockedOutput keeps content that failed moderation for review,
+// along with the request that produced it.
+func (s *Server) recordBlockedOutput(r *http.Request, kind string, mod safety.Moderation, input map[string]string) {
+	findings, _ := json.Marshal(mod.Findings)
+	raw, _ := json.Marshal(input)
+	err := logic.RecordFlaggedRequest(r.Context(), s.pool, logic.FlaggedRequest{
+		UserID:   currentUser(r).ID,
+		Kind:     kind + "_output",
+		Action:   "rejected",
+		Findings: findings,
+		Input:    raw,
+	})
+	if err != nil {
+		logFor(r).Error("recording blocked output failed", "err", err)
+	}
+}
+
+// moderationLabel is the verdict label of the moderation metric: passed,
+// or the rule of the first finding.
+func moderationLabel(mod safety.Moderation) string {
+	if mod.Passed || len(mod.Findings) == 0 {
+		return "passed"
+	}
+	return string(mod.Findings[0].Rule)
+}
@@ -0,0 +1,207 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+func (s *Server) handleListOrgs(w http.ResponseWriter, r *http.Request) {
+	orgs, err := logic.ListOrgs(r.Context(), s.pool, currentUser(r).ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"organizations": orgs})
+}
+
+func (s *Server) handleCreateOrg(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	org, err := logic.CreateOrg(r.Context(), s.pool, currentUser(r).ID, strings.TrimSpace(req.Name))
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 201, org)
+}
+
+func (s *Server) handleGetOrg(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("orgID")
+	if _, ok := s.requireOrgRole(w, r, orgID, false); !ok {
+		return
+	}
+	org, members, err := logic.GetOrg(r.Context(), s.pool, orgID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"organization": org, "members": members})
+}
+
+func (s *Server) handleAddOrgMember(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("orgID")
+	callerRole, ok := s.requireOrgRole(w, r, orgID, true)
+	if !ok {
+		return
+	}
+	var req struct {
+		Email      string `json:"email"`
+		Role       string `json:"role"`
+		SpendLimit *int   `json:"spendLimit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	if req.Role == "" { req.Role = logic.RoleTeacher }
+	if !logic.ValidRole(req.Role) || (req.SpendLimit != nil && *req.SpendLimit < 0) {
+		httpError(w, r, "Invalid role or spending limit", 400)
+		return
+	}
+	if req.Role == logic.RoleOwner && callerRole != logic.RoleOwner {
+		httpError(w, r, "Only owners can add owners", 403)
+		return
+	}
+	m, err := logic.AddMember(r.Context(), s.pool, orgID, req.Email, req.Role, req.SpendLimit)
+	if errors.Is(err, logic.ErrUserNotFound) {
+		httpError(w, r, "No account with that email", 404)
+		return
+	}
+	if errors.Is(err, logic.ErrAlreadyMember) {
+		httpError(w, r, "Already a member; change their role instead", 409)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 201, m)
+}
+
+func (s *Server) handleUpdateOrgMember(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID := r.PathValue("orgID"), r.PathValue("userID")
+	if !validID(memberID) {
+		httpError(w, r, "Member not found", 404)
+		return
+	}
+	callerRole, ok := s.requireOrgRole(w, r, orgID, true)
+	if !ok {
+		return
+	}
+	var req struct {
+		Role       string `json:"role"`
+		SpendLimit *int   `json:"spendLimit"`
+		ResetSpent bool   `json:"resetSpent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !logic.ValidRole(req.Role) ||
+		(req.SpendLimit != nil && *req.SpendLimit < 0) {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	if callerRole != logic.RoleOwner {
+		current, err := logic.MemberRole(r.Context(), s.pool, orgID, memberID)
+		if err == nil && (current == logic.RoleOwner || req.Role == logic.RoleOwner) {
+			httpError(w, r, "Only owners can change owners", 403)
+			return
+		}
+	}
+	err := logic.UpdateMember(r.Context(), s.pool, orgID, memberID, req.Role, req.SpendLimit, req.ResetSpent)
+	if !writeOrgError(w, r, err) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleRemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID := r.PathValue("orgID"), r.PathValue("userID")
+	if !validID(memberID) {
+		httpError(w, r, "Member not found", 404)
+		return
+	}
+	callerID := currentUser(r).ID
+	// Members may always leave; removing someone else needs admin rights.
+	callerRole, ok := s.requireOrgRole(w, r, orgID, memberID != callerID)
+	if !ok {
+		return
+	}
+	if callerRole != logic.RoleOwner && memberID != callerID {
+		if current, err := logic.MemberRole(r.Context(), s.pool, orgID, memberID); err == nil && current == logic.RoleOwner {
+			httpError(w, r, "Only owners can remove owners", 403)
+			return
+		}
+	}
+	if !writeOrgError(w, r, logic.RemoveMember(r.Context(), s.pool, orgID, memberID)) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleTransferOrgCredits(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("orgID")
+	if _, ok := s.requireOrgRole(w, r, orgID, true); !ok {
+		return
+	}
+	var req struct {
+		Amount int `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	err := logic.TransferToOrg(r.Context(), s.pool, orgID, currentUser(r).ID, req.Amount)
+	if errors.Is(err, logic.ErrInsufficientCredits) {
+		httpError(w, r, "Insufficient credits", 402)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	org, _, err := logic.GetOrg(r.Context(), s.pool, orgID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]int{"credits": org.CreditBalance})
+}
+
+// requireOrgRole looks up the caller's role in orgID and writes a 404 if
+// orgID is malformed, or a 403 if they are not a member, or not an
+// owner/admin when manage is set.
+func (s *Server) requireOrgRole(w http.ResponseWriter, r *http.Request, orgID string, manage bool) (string, bool) {
+	if !validID(orgID) {
+		httpError(w, r, "Organization not found", 404)
+		return "", false
+	}
+	role, err := logic.MemberRole(r.Context(), s.pool, orgID, currentUser(r).ID)
+	if errors.Is(err, logic.ErrNotOrgMember) || (err == nil && manage && !logic.CanManage(role)) {
+		httpError(w, r, "Forbidden", 403)
+		return "", false
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return "", false
+	}
+	return role, true
+}
+
+func writeOrgError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, logic.ErrNotOrgMember):
+		httpError(w, r, "Member not found", 404)
+	case errors.Is(err, logic.ErrLastOwner):
+		httpError(w, r, "Organization must keep at least one owner", 409)
+	default:
+		httpError(w, r, "Database error", 500)
+	}
+	return true
+}
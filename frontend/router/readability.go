@@ -0,0 +1,100 @@
+package router
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"github.com/ElvanForge/lesson-forge/backend/prompts"
+	"github.com/ElvanForge/lesson-forge/backend/readability"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+)
+
+// readabilityReport is the readability part of a generation response.
+type readabilityReport struct {
+	readability.Stats
+	TargetGrade *int `json:"targetGrade,omitempty"`
+	// Adjustment is what the content would still need to suit the target
+	// (simplify or enrich), and Rewritten the rewrite already applied.
+	Adjustment readability.Adjustment `json:"adjustment,omitempty"`
+	Rewritten  readability.Adjustment `json:"rewritten,omitempty"`
+}
+
+// measure reports how hard content reads against grade, or nil when it
+// can't be measured because it isn't English.
+func (s *Server) measure(content, grade, locale string) *readabilityReport {
+	if !readability.English(locale) {
+		return nil
+	}
+	rep := &readabilityReport{Stats: readability.Analyze(content)}
+	if target, ok := prompts.GradeLevel(grade); ok && rep.Words > 0 {
+		rep.TargetGrade = &target
+		rep.Adjustment = readability.Compare(rep.Grade, target, s.cfg.ReadabilityTolerance)
+	}
+	return rep
+}
+
+// adjustReadability rewrites a new generation once toward its grade when
+// it reads more than READABILITY_TOLERANCE grades off and
+// READABILITY_REWRITE is on. The rewrite is kept only if it passes
+// moderation, keeps every section and reads closer to the target; then
+// content, usage and mod are updated to match it.
+func (s *Server) adjustReadability(r *http.Request, p logic.AIProvider, mode, grade, locale string, content *string, usage *logic.Usage, mod *safety.Moderation) *readabilityReport {
+	rep := s.measure(*content, grade, locale)
+	if rep == nil || rep.Adjustment == readability.OnTarget || !s.cfg.ReadabilityRewrite {
+		return rep
+	}
+
+	tmpl, prompt, err := s.buildPrompt(prompts.Rewrite, locale, grade, currentUser(r).ID, prompts.Data{
+		Grade:        grade,
+		Locale:       locale,
+		Content:      *content,
+		Adjust:       string(rep.Adjustment),
+		ReadingGrade: rep.Grade,
+	})
+	if err != nil {
+		logFor(r).Error("building rewrite prompt failed", "err", err)
+		return rep
+	}
+	text, u, rewriteMod, err := s.generateModerated(r, p, moderatedRequest{
+		kind: "rewrite", mode: mode, template: tmpl.ID(),
+		prompt: prompt,
+		grade:  grade, locale: locale,
+	})
+	usage.Add(u)
+	after := s.measure(text, grade, locale)
+	outcome := rewriteOutcome(err, rewriteMod, mode, *content, text, rep, after)
+	s.metrics.rewrites.Inc(string(rep.Adjustment), outcome)
+	logFor(r).Info("readability rewrite", "adjust", rep.Adjustment, "from_grade", rep.Grade, "to_grade", after.Grade, "outcome", outcome)
+	if outcome != "kept" {
+		return rep
+	}
+
+	rewriteMod.Attempts += mod.Attempts
+	*content, *mod = text, rewriteMod
+	after.Rewritten = rep.Adjustment
+	return after
+}
+
+// rewriteOutcome decides whether a readability rewrite of original is
+// kept: the call must have succeeded, and the rewrite must pass
+// moderation, keep every section and read closer to the target than
+// before. It returns "kept" or the reason it isn't, for the rewrite metric.
+func rewriteOutcome(err error, mod safety.Moderation, mode, original, rewrite string, before, after *readabilityReport) string {
+	switch {
+	case err != nil:
+		return "ai_error"
+	case !mod.Passed:
+		return "moderation_failed"
+	case len(logic.ParseContent(mode, rewrite).Sections) != len(logic.ParseContent(mode, original).Sections):
+		return "structure_changed"
+	case offBy(after.Grade, *before.TargetGrade) >= offBy(before.Grade, *before.TargetGrade):
+		return "not_closer"
+	}
+	return "kept"
+}
+
+// offBy is how many grades a text measured at grade is from target.
+func offBy(grade float64, target int) float64 {
+	return math.Abs(grade - float64(max(target, readability.MinGrade)))
+}
@@ -0,0 +1,53 @@
+package router
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ElvanForge/lesson-forge/backend/readability"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+)
+
+func TestRewriteOutcome(t *testing.T) {
+	const (
+		lesson = "# Lesson: Plants\n## Objectives\nLearn.\n## Summary of Tasks\nGrow.\n## Materials & Equipment\nSeeds."
+		merged = "# Lesson: Plants\n## Objectives\nLearn and grow.\n## Materials & Equipment\nSeeds."
+		slides = "# Plants\nSeeds\n---\n# Roots\nWater\n---\n# Leaves\nSun"
+	)
+	report := func(grade float64, target int) *readabilityReport {
+		return &readabilityReport{Stats: readability.Stats{Grade: grade}, TargetGrade: &target}
+	}
+	passed, failed := safety.Moderation{Passed: true}, safety.Moderation{Passed: false}
+
+	cases := []struct {
+		name          string
+		err           error
+		mod           safety.Moderation
+		mode          string
+		rewrite       string
+		before, after *readabilityReport
+		want          string
+	}{
+		{"simplified", nil, passed, "lesson", lesson, report(11, 4), report(6, 4), "kept"},
+		{"enriched", nil, passed, "lesson", lesson, report(5, 10), report(9, 10), "kept"},
+		{"overshot but closer", nil, passed, "lesson", lesson, report(12, 6), report(3, 6), "kept"},
+		{"kindergarten compared at MinGrade", nil, passed, "lesson", lesson, report(9, 0), report(4, 0), "kept"},
+		{"same distance", nil, passed, "lesson", lesson, report(10, 6), report(2, 6), "not_closer"},
+		{"further", nil, passed, "lesson", lesson, report(10, 6), report(11, 6), "not_closer"},
+		{"below MinGrade is no closer", nil, passed, "lesson", lesson, report(2, 0), report(1, 0), "not_closer"},
+		{"section dropped", nil, passed, "lesson", merged, report(11, 4), report(6, 4), "structure_changed"},
+		{"slide added", nil, passed, "ppt", slides + "\n---\n# Flowers\nBees", report(11, 4), report(6, 4), "structure_changed"},
+		{"slides kept", nil, passed, "ppt", slides, report(11, 4), report(6, 4), "kept"},
+		{"moderation failed", nil, failed, "lesson", lesson, report(11, 4), report(6, 4), "moderation_failed"},
+		{"ai error", errors.New("timeout"), passed, "lesson", "", report(11, 4), report(0, 4), "ai_error"},
+	}
+	for _, c := range cases {
+		original := lesson
+		if c.mode == "ppt" {
+			original = slides
+		}
+		if got := rewriteOutcome(c.err, c.mod, c.mode, original, c.rewrite, c.before, c.after); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package router holds the HTTP API. The Vercel function in api/ and the
+// standalone cmd/server binary both serve the same Server, so local
+// development runs exactly the routes production does.
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ElvanForge/lesson-forge/backend/config"
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"github.com/ElvanForge/lesson-forge/backend/prompts"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Server routes API requests to their handlers. Build one with New or
+// NewFromConfig; it is safe for concurrent use.
+type Server struct {
+	cfg      *config.Config
+	pool     *pgxpool.Pool
+	store    logic.Storage
+	verifier *logic.TokenVerifier
+	ai       logic.AIConfig
+	prompts  *prompts.Registry
+	screener *safety.Screener
+	proxies  *proxyTrust
+	cors     *corsPolicy
+	metrics  *serverMetrics
+	aiHealth aiHealth
+	mux      *http.ServeMux
+	handler  http.Handler
+}
+
+func New(cfg *config.Config, pool *pgxpool.Pool, store logic.Storage, verifier *logic.TokenVerifier) *Server {
+	s := &Server{cfg: cfg, pool: pool, store: store, verifier: verifier, ai: aiConfig(cfg), prompts: prompts.Default()}
+	s.screener = &safety.Screener{Policy: safety.Policy(cfg.InputScreenPolicy)}
+	s.proxies = newProxyTrust(cfg.TrustedProxies)
+	s.cors = newCORSPolicy(cfg.Origins, cfg.CORSMaxAge)
+	s.metrics = newServerMetrics(pool)
+	s.mux = s.routes()
+	s.handler = s.withRequestLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cors.handle(w, r, s.mux) {
+			s.mux.ServeHTTP(w, r)
+		}
+	}))
+	return s
+}
+
+// NewFromConfig connects to the database and sets up storage and token
+// verification as described by cfg.
+func NewFromConfig(ctx context.Context, cfg *config.Config) (*Server, error) {
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("database: %w", err)
+	}
+	store, err := logic.NewStorage(storageConfig(cfg))
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	verifier := logic.NewSupabaseVerifier(cfg.SupabaseURL, cfg.JWTSecret)
+	if cfg.JWKSURL != "" { verifier.JWKSURL = cfg.JWKSURL }
+	return New(cfg, pool, store, verifier), nil
+}
+
+// storageConfig returns the settings for logic.NewStorage.
+func storageConfig(c *config.Config) logic.StorageConfig {
+	return logic.StorageConfig{
+		Backend:     c.StorageBackend,
+		Bucket:      c.StorageBucket,
+		SupabaseURL: c.SupabaseURL,
+		ServiceKey:  c.ServiceKey,
+		S3Endpoint:  c.S3Endpoint,
+		S3Region:    c.S3Region,
+		S3AccessKey: c.S3AccessKey,
+		S3SecretKey: c.S3SecretKey,
+		LocalDir:    c.LocalDir,
+		LocalURL:    c.LocalBaseURL,
+		SigningKey:  c.LocalSigningKey,
+	}
+}
+
+// aiConfig returns the provider settings.
+func aiConfig(c *config.Config) logic.AIConfig {
+	return logic.AIConfig{
+		GeminiKey:   c.GeminiKey,
+		DeepSeekKey: c.DeepSeekKey,
+		Mock:        c.MockAI,
+		Location:    c.Location,
+		Transport:   tracedTransport{},
+	}
+}
+
+// Close releases the database pool.
+func (s *Server) Close() {
+	s.pool.Close()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() *http.ServeMux {
+	m := http.NewServeMux()
+	m.HandleFunc("GET /metrics", s.handleMetrics)
+	// Probes are also served under /api, the only prefix Vercel routes
+	// to this function.
+	for _, prefix := range []string{"", "/api"} {
+		m.HandleFunc("GET "+prefix+"/healthz", s.handleHealthz)
+		m.HandleFunc("GET "+prefix+"/readyz", s.handleReadyz)
+	}
+	m.Handle("POST /api/generate", s.authMiddleware(logic.ScopeGenerate, s.generationLimiter(http.HandlerFunc(s.handleGenerate))))
+	m.Handle("POST /api/generations/{id}/sections/{index}", s.authMiddleware(logic.ScopeGenerate, s.generationLimiter(http.HandlerFunc(s.handleEditSection))))
+	m.Handle("GET /api/generations", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleListGenerations)))
+	m.Handle("PATCH /api/generations/{id}", s.authMiddleware("", http.HandlerFunc(s.handleUpdateGeneration)))
+	m.Handle("GET /api/tags", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleListTags)))
+	m.Handle("GET /api/folders", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleListFolders)))
+	m.Handle("POST /api/folders", s.authMiddleware("", http.HandlerFunc(s.handleCreateFolder)))
+	m.Handle("PATCH /api/folders/{folderID}", s.authMiddleware("", http.HandlerFunc(s.handleRenameFolder)))
+	m.Handle("DELETE /api/folders/{folderID}", s.authMiddleware("", http.HandlerFunc(s.handleDeleteFolder)))
+	m.Handle("GET /api/generations/{id}/shares", s.authMiddleware("", http.HandlerFunc(s.handleListShares)))
+	m.Handle("POST /api/generations/{id}/shares", s.authMiddleware("", http.HandlerFunc(s.handleCreateShare)))
+	m.Handle("DELETE /api/generations/{id}/shares/{shareID}", s.authMiddleware("", http.HandlerFunc(s.handleRevokeShare)))
+	m.HandleFunc("GET /api/shared/{token}", s.handleViewShare)
+	m.Handle("POST /api/shared/{token}/copy", s.authMiddleware("", http.HandlerFunc(s.handleCopyShare)))
+	m.Handle("GET /api/generations/{id}/versions", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleListVersions)))
+	m.Handle("POST /api/generations/{id}/versions/{version}/restore", s.authMiddleware("", http.HandlerFunc(s.handleRestoreVersion)))
+	m.Handle("GET /api/generations/{id}/diff", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleDiffVersions)))
+	m.Handle("POST /api/generations/{id}/render", s.authMiddleware(logic.ScopeGenerate, http.HandlerFunc(s.handleRender)))
+	m.Handle("GET /api/generations/{id}/download", s.authMiddleware(logic.ScopeReadHistory, http.HandlerFunc(s.handleDownload)))
+	m.Handle("GET /api/user/credits", s.authMiddleware(logic.ScopeReadCredits, http.HandlerFunc(s.handleGetCredits)))
+	m.Handle("POST /api/credits/redeem", s.authMiddleware("", http.HandlerFunc(s.handleRedeemPromo)))
+	m.Handle("GET /api/referral", s.authMiddleware("", http.HandlerFunc(s.handleGetReferral)))
+	m.Handle("POST /api/referral/claim", s.authMiddleware("", http.HandlerFunc(s.handleClaimReferral)))
+	if s.cfg.StripeWebhookSecret != "" {
+		m.HandleFunc("POST /api/webhooks/stripe", s.handleStripeWebhook)
+	}
+
+	m.Handle("GET /api/keys", s.authMiddleware("", http.HandlerFunc(s.handleListAPIKeys)))
+	m.Handle("POST /api/keys", s.authMiddleware("", http.HandlerFunc(s.handleCreateAPIKey)))
+	m.Handle("DELETE /api/keys/{keyID}", s.authMiddleware("", http.HandlerFunc(s.handleRevokeAPIKey)))
+
+	if local, ok := s.store.(*logic.LocalStorage); ok {
+		m.HandleFunc("GET /api/files/{key...}", localFileHandler(local))
+	}
+
+	m.Handle("GET /api/admin/usage", s.authMiddleware("", s.adminOnly(http.HandlerFunc(s.handleUsageReport))))
+	m.Handle("GET /api/admin/flags", s.authMiddleware("", s.adminOnly(http.HandlerFunc(s.handleListFlags))))
+	m.Handle("POST /api/admin/flags/{flagID}/review", s.authMiddleware("", s.adminOnly(http.HandlerFunc(s.handleReviewFlag))))
+
+	m.Handle("GET /api/orgs", s.authMiddleware("", http.HandlerFunc(s.handleListOrgs)))
+	m.Handle("POST /api/orgs", s.authMiddleware("", http.HandlerFunc(s.handleCreateOrg)))
+	m.Handle("GET /api/orgs/{orgID}", s.authMiddleware("", http.HandlerFunc(s.handleGetOrg)))
+	m.Handle("POST /api/orgs/{orgID}/members", s.authMiddleware("", http.HandlerFunc(s.handleAddOrgMember)))
+	m.Handle("PATCH /api/orgs/{orgID}/members/{userID}", s.authMiddleware("", http.HandlerFunc(s.handleUpdateOrgMember)))
+	m.Handle("DELETE /api/orgs/{orgID}/members/{userID}", s.authMiddleware("", http.HandlerFunc(s.handleRemoveOrgMember)))
+	m.Handle("POST /api/orgs/{orgID}/credits", s.authMiddleware("", http.HandlerFunc(s.handleTransferOrgCredits)))
+	return m
+}
+
+// localFileHandler serves files written by the local storage backend.
+func localFileHandler(local *logic.LocalStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		if !local.Verify(key, r.URL.Query().Get("expires"), r.URL.Query().Get("sig")) {
+			httpError(w, r, "Link expired or invalid", 403)
+			return
+		}
+		f, err := local.Open(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	}
+}
+
+// appURL is the public frontend origin used in links we hand out.
+func (s *Server) appURL() string {
+	return strings.TrimSuffix(s.cfg.PublicAppURL, "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// authMiddleware authenticates the request with a Supabase access token or
+// an API key and stores the caller in the request context. API keys are
+// only accepted on routes with a scope, and only if the key carries it;
+// routes registered with an empty scope need a browser session.
+func (s *Server) authMiddleware(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			httpError(w, r, "Unauthorized", 401)
+			return
+		}
+		var user logic.User
+		var err error
+		ctx, span := tracer.Start(r.Context(), "auth.verify")
+		if strings.HasPrefix(token, logic.APIKeyPrefix) {
+			span.SetAttributes(attribute.String("auth.method", "api_key"))
+			user, err = logic.AuthenticateAPIKey(ctx, s.pool, token)
+		} else {
+			span.SetAttributes(attribute.String("auth.method", "jwt"))
+			user, err = s.verifier.Verify(ctx, token)
+		}
+		endSpan(span, err)
+		if err != nil {
+			httpError(w, r, "Unauthorized", 401)
+			return
+		}
+		if user.APIKeyID != "" && (scope == "" || !user.Can(scope)) {
+			httpError(w, r, "API key lacks the required scope", 403)
+			return
+		}
+		setRequestUser(r.Context(), user.ID)
+		next.ServeHTTP(w, r.WithContext(logic.WithUser(r.Context(), user)))
+	})
+}
+
+// generationLimiter enforces the per-user and per-IP token buckets and the
+// per-user cap on concurrent generations, answering 429 with Retry-After.
+func (s *Server) generationLimiter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := currentUser(r).ID
+		err := logic.TakeTokens(r.Context(), s.pool,
+			logic.Limit{Key: "user:" + userID, Bucket: logic.UserGenerateBucket},
+			logic.Limit{Key: "ip:" + s.clientIP(r), Bucket: logic.IPGenerateBucket})
+		var release func()
+		if err == nil {
+			release, err = logic.AcquireGenerationSlot(r.Context(), s.pool, userID)
+		}
+		if le, ok := logic.IsLimitError(err); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(le.RetryAfter.Seconds())))
+			httpError(w, r, "Too many requests: "+le.Reason, 429)
+			return
+		}
+		if err != nil {
+			httpError(w, r, "Database error", 500)
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+var idPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validID reports whether id is a UUID, so malformed path and body IDs can
+// be turned away before they fail a ::uuid cast in the database.
+func validID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+func currentUser(r *http.Request) logic.User {
+	u, _ := logic.UserFrom(r.Context())
+	return u
+}
@@ -0,0 +1,39 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"github.com/ElvanForge/lesson-forge/backend/safety"
+)
+
+// screen runs the input screening stage over fields before they go into
+// a prompt, and records anything it finds for review. Under the sanitize
+// policy the fields are rewritten in place.
+func (s *Server) screen(r *http.Request, kind string, fields ...safety.Field) safety.Verdict {
+	input := map[string]string{}
+	for _, f := range fields {
+		input[f.Name] = *f.Value
+	}
+	v := s.screener.Screen(fields...)
+	if len(v.Findings) == 0 {
+		return v
+	}
+	s.metrics.screened.Inc(kind, v.Action)
+	logFor(r).Warn("input screening", "kind", kind, "action", v.Action, "findings", v.Findings)
+
+	findings, _ := json.Marshal(v.Findings)
+	raw, _ := json.Marshal(input)
+	err := logic.RecordFlaggedRequest(r.Context(), s.pool, logic.FlaggedRequest{
+		UserID:   currentUser(r).ID,
+		Kind:     kind,
+		Action:   v.Action,
+		Findings: findings,
+		Input:    raw,
+	})
+	if err != nil {
+		logFor(r).Error("recording flagged request failed", "err", err)
+	}
+	return v
+}
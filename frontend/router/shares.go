@@ -0,0 +1,134 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+func (s *Server) handleListShares(w http.ResponseWriter, r *http.Request) {
+	gen, ok := s.ownedGeneration(w, r)
+	if !ok {
+		return
+	}
+	shares, err := logic.ListShares(r.Context(), s.pool, gen.ID)
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 200, map[string]interface{}{"shares": shares})
+}
+
+// handleCreateShare issues a view-only link to a generation, optionally
+// expiring after expiresInDays.
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ExpiresInDays int `json:"expiresInDays"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ExpiresInDays < 0 {
+			httpError(w, r, "Invalid request", 400)
+			return
+		}
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+	id := r.PathValue("id")
+	if !validID(id) {
+		httpError(w, r, "Generation not found", 404)
+		return
+	}
+	share, err := logic.CreateShare(r.Context(), s.pool, id, currentUser(r).ID, expiresAt)
+	if errors.Is(err, logic.ErrGenerationNotFound) {
+		httpError(w, r, "Generation not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	writeJSON(w, 201, map[string]interface{}{
+		"share": share,
+		"url":   s.appURL() + "/shared/" + share.Token,
+	})
+}
+
+func (s *Server) handleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	id, shareID := r.PathValue("id"), r.PathValue("shareID")
+	if !validID(id) || !validID(shareID) {
+		httpError(w, r, "Share not found", 404)
+		return
+	}
+	err := logic.RevokeShare(r.Context(), s.pool, id, shareID, currentUser(r).ID)
+	if errors.Is(err, logic.ErrShareNotFound) {
+		httpError(w, r, "Share not found", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleViewShare is the public, unauthenticated view of a shared lesson.
+func (s *Server) handleViewShare(w http.ResponseWriter, r *http.Request) {
+	lesson, err := logic.OpenShare(r.Context(), s.pool, r.PathValue("token"), true)
+	if errors.Is(err, logic.ErrShareNotFound) {
+		httpError(w, r, "This link has expired or was revoked", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writeJSON(w, 200, lesson)
+}
+
+// handleCopyShare copies a shared lesson into the caller's library. The
+// content is already generated, so no credits are spent.
+func (s *Server) handleCopyShare(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).ID
+	lesson, err := logic.OpenShare(r.Context(), s.pool, r.PathValue("token"), false)
+	if errors.Is(err, logic.ErrShareNotFound) {
+		httpError(w, r, "This link has expired or was revoked", 404)
+		return
+	}
+	if err != nil {
+		httpError(w, r, "Database error", 500)
+		return
+	}
+
+	file, err := s.render(r.Context(), logic.DefaultFormat(lesson.Mode), userID, lesson.Content)
+	gen := logic.Generation{
+		UserID:     userID,
+		Prompt:     lesson.Prompt,
+		Mode:       lesson.Mode,
+		Grade:      lesson.Grade,
+		Duration:   lesson.Duration,
+		Content:    lesson.Content,
+		Structure:  lesson.Structure,
+		CopiedFrom: &lesson.GenerationID,
+	}
+	if err == nil {
+		gen.FilePath = fmt.Sprintf("%s/%d_%s", userID, time.Now().Unix(), file.Name)
+		err = s.put(r.Context(), gen.FilePath, file)
+	}
+	if err == nil {
+		err = s.saveGeneration(r.Context(), &gen)
+	}
+	if err != nil {
+		logFor(r).Error("copying shared lesson failed", "err", err)
+		httpError(w, r, "Could not copy lesson", 500)
+		return
+	}
+	writeJSON(w, 201, map[string]interface{}{"id": gen.ID})
+}
@@ -0,0 +1,223 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer goes through the global provider, so spans started before
+// SetupTracing (or without it) are simply dropped.
+var tracer = otel.Tracer("github.com/ElvanForge/lesson-forge/backend/router")
+
+// SetupTracing sends spans over OTLP/HTTP to endpoint, such as a local
+// collector at http://localhost:4318. With no endpoint tracing stays off.
+// The returned function flushes buffered spans and must be called before
+// the process exits.
+func SetupTracing(ctx context.Context, endpoint, serviceName string) (shutdown func(context.Context) error, err error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// startRequestSpan opens the server span for r, continuing a trace started
+// by the caller if it sent a traceparent header.
+func startRequestSpan(r *http.Request, clientIP string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("http.request.method", r.Method),
+		attribute.String("url.path", r.URL.Path),
+		attribute.String("client.address", clientIP),
+	))
+}
+
+// endRequestSpan names the span after the matched route, which is only
+// known once the mux has run, and closes it.
+func endRequestSpan(span trace.Span, r *http.Request, status int) {
+	if r.Pattern != "" {
+		span.SetName(r.Pattern)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
+// endSpan marks span failed if err is set and closes it. A transport
+// error's request URL is left out: it can carry keys or signatures, and
+// spans leave the process.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = fmt.Errorf("%s %s: %w", uerr.Op, redactURL(uerr.URL), uerr.Err)
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// redactURL is raw without its query string, or "" if it doesn't parse.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// tracedTransport runs each outbound request in a client span and passes
+// the trace on in its headers, so calls to the AI providers show up under
+// the request that made them.
+type tracedTransport struct {
+	base http.RoundTripper // nil uses http.DefaultTransport
+}
+
+func (t tracedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(r.Context(), "HTTP "+r.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", r.Method),
+		attribute.String("server.address", r.URL.Hostname()),
+		attribute.String("url.path", r.URL.Path),
+	))
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(r)
+	if err != nil {
+		endSpan(span, &url.Error{Op: r.Method, URL: r.URL.String(), Err: err})
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	span.End()
+	return resp, nil
+}
+
+// traceID is the current trace for log lines, or "" when not tracing.
+func traceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// debit is logic.DebitCredits, traced.
+func (s *Server) debit(ctx context.Context, acct logic.Account, amount int, reason string) error {
+	ctx, span := tracer.Start(ctx, "credits.debit", trace.WithAttributes(
+		attribute.Int("credits.amount", amount),
+		attribute.String("org.id", acct.OrgID),
+		attribute.String("credits.reason", reason),
+	))
+	err := logic.DebitCredits(ctx, s.pool, acct, amount, reason)
+	endSpan(span, err)
+	return err
+}
+
+// generate calls the AI provider inside a span carrying the model and
+// token usage it reports, and times the call.
+func (s *Server) generate(ctx context.Context, p logic.AIProvider, prompt string, genImage bool) (logic.Completion, error) {
+	ctx, span := tracer.Start(ctx, "ai.generate", trace.WithAttributes(
+		attribute.String("gen_ai.system", p.Name()),
+		attribute.Int("gen_ai.request.prompt_chars", len(prompt)),
+		attribute.Bool("gen_ai.request.images", genImage),
+	))
+	start := time.Now()
+	c, err := p.GenerateContent(ctx, prompt, genImage)
+	c.Latency = time.Since(start)
+	span.SetAttributes(
+		attribute.String("gen_ai.response.model", c.Model),
+		attribute.Int("gen_ai.usage.input_tokens", c.InputTokens),
+		attribute.Int("gen_ai.usage.output_tokens", c.OutputTokens),
+	)
+	endSpan(span, err)
+	return c, err
+}
+
+// render is logic.Render, timed and traced.
+func (s *Server) render(ctx context.Context, format, userID, content string) (logic.Rendered, error) {
+	_, span := tracer.Start(ctx, "render", trace.WithAttributes(attribute.String("render.format", format)))
+	start := time.Now()
+	file, err := logic.Render(format, userID, content)
+	if err == nil {
+		s.metrics.renderDuration.Observe(time.Since(start).Seconds(), format)
+		span.SetAttributes(attribute.Int("render.bytes", len(file.Data)))
+	}
+	endSpan(span, err)
+	return file, err
+}
+
+// put uploads a rendered file, timed and traced.
+func (s *Server) put(ctx context.Context, key string, file logic.Rendered) error {
+	ctx, span := tracer.Start(ctx, "storage.put", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("storage.key", key),
+		attribute.Int("storage.bytes", len(file.Data)),
+	))
+	start := time.Now()
+	err := s.store.Put(ctx, key, file.Data, file.ContentType)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	s.metrics.uploadDuration.Observe(time.Since(start).Seconds(), outcome)
+	endSpan(span, err)
+	return err
+}
+
+// saveGeneration is logic.SaveGeneration, traced.
+func (s *Server) saveGeneration(ctx context.Context, gen *logic.Generation) error {
+	ctx, span := tracer.Start(ctx, "generations.insert")
+	err := logic.SaveGeneration(ctx, s.pool, gen)
+	if err == nil {
+		span.SetAttributes(attribute.String("generation.id", gen.ID))
+	}
+	endSpan(span, err)
+	return err
+}
+
+// saveVersion is logic.SaveVersion, traced.
+func (s *Server) saveVersion(ctx context.Context, gen logic.Generation, note string) (int, error) {
+	ctx, span := tracer.Start(ctx, "generations.save_version", trace.WithAttributes(attribute.String("generation.id", gen.ID)))
+	version, err := logic.SaveVersion(ctx, s.pool, gen, note)
+	endSpan(span, err)
+	return version, err
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestTracedTransport(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx, parent := tracer.Start(context.Background(), "ai.generate")
+	var traceparent string
+	ok := tracedTransport{base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		traceparent = r.Header.Get("traceparent")
+		return httptest.NewRecorder().Result(), nil
+	})}
+	failing := tracedTransport{base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})}
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://ai.example.com/v1/generate?key=secret-key", nil)
+	if _, err := ok.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := failing.RoundTrip(req); err == nil {
+		t.Fatal("failing transport returned no error")
+	}
+	parent.End()
+
+	if !strings.Contains(traceparent, parent.SpanContext().TraceID().String()) {
+		t.Errorf("traceparent %q does not continue trace %s", traceparent, parent.SpanContext().TraceID())
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("RoundTrip modified the caller's request")
+	}
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	for _, s := range spans[:2] {
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the caller's span", s.Name())
+		}
+	}
+	for _, ev := range spans[1].Events() {
+		for _, a := range ev.Attributes {
+			if strings.Contains(a.Value.Emit(), "secret-key") {
+				t.Errorf("span event %s=%q carries the query string", a.Key, a.Value.Emit())
+			}
+		}
+	}
+	if desc := spans[1].Status().Description; strings.Contains(desc, "secret-key") || !strings.Contains(desc, "connection refused") {
+		t.Errorf("span status %q, want the cause without the query string", desc)
+	}
+}
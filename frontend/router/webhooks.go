@@ -0,0 +1,133 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElvanForge/lesson-forge/backend/logic"
+)
+
+// stripeTolerance is how old a webhook signature may be, as Stripe's own
+// libraries default to, so a captured request can't be replayed later.
+const stripeTolerance = 5 * time.Minute
+
+var errBadSignature = errors.New("invalid Stripe signature")
+
+// handleStripeWebhook credits purchases made through the Stripe Payment
+// Link. The link must carry the buyer's user ID as client_reference_id
+// (the app appends it) and the credits it sells as metadata "credits".
+// Completing a purchase also pays out the referral bonus on the buyer's
+// first one.
+func (s *Server) handleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	if err := verifyStripeSignature(payload, r.Header.Get("Stripe-Signature"), s.cfg.StripeWebhookSecret, time.Now()); err != nil {
+		logFor(r).Warn("rejected stripe webhook", "err", err)
+		httpError(w, r, "Invalid signature", 400)
+		return
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID                string            `json:"id"`
+				ClientReferenceID string            `json:"client_reference_id"`
+				AmountTotal       int               `json:"amount_total"`
+				PaymentStatus     string            `json:"payment_status"`
+				Metadata          map[string]string `json:"metadata"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		httpError(w, r, "Invalid request", 400)
+		return
+	}
+	session := event.Data.Object
+	paid := event.Type == "checkout.session.async_payment_succeeded" ||
+		event.Type == "checkout.session.completed" && session.PaymentStatus == "paid"
+	if !paid {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	credits, err := strconv.Atoi(session.Metadata["credits"])
+	if err != nil || credits <= 0 || !validID(session.ClientReferenceID) {
+		// Answering with an error keeps the event failing, and visible, in
+		// the Stripe dashboard until the link is fixed and it is resent.
+		logFor(r).Error("stripe checkout without user or credits", "event_id", event.ID, "session_id", session.ID)
+		httpError(w, r, "Checkout session lacks client_reference_id or credits metadata", 422)
+		return
+	}
+	err = logic.RecordPurchase(r.Context(), s.pool, logic.Purchase{
+		SessionID:   session.ID,
+		UserID:      session.ClientReferenceID,
+		AmountCents: session.AmountTotal,
+		Credits:     credits,
+	})
+	if errors.Is(err, logic.ErrUserNotFound) {
+		// Retrying can't create the account, so acknowledge the event and
+		// leave the payment to be resolved by hand.
+		logFor(r).Error("stripe checkout for unknown user", "event_id", event.ID, "session_id", session.ID, "user_id", session.ClientReferenceID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		logFor(r).Error("recording purchase failed", "err", err, "session_id", session.ID)
+		httpError(w, r, "Database error", 500)
+		return
+	}
+	logFor(r).Info("purchase completed", "session_id", session.ID, "user_id", session.ClientReferenceID, "credits", credits)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyStripeSignature checks a Stripe-Signature header ("t=...,v1=...")
+// against the endpoint secret, as described at
+// https://docs.stripe.com/webhooks#verify-manually.
+func verifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	if secret == "" {
+		return errors.New("STRIPE_WEBHOOK_SECRET is not set")
+	}
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return errBadSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return errors.New("Stripe signature timestamp outside tolerance")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errBadSignature
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func stripeHeader(secret string, t time.Time, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", t.Unix(), payload)
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	const secret, payload = "whsec_test", `{"id":"evt_1","type":"checkout.session.completed"}`
+	now := time.Unix(1700000000, 0)
+	good := stripeHeader(secret, now, payload)
+
+	cases := []struct {
+		name    string
+		payload string
+		header  string
+		secret  string
+		ok      bool
+	}{
+		{"valid", payload, good, secret, true},
+		{"valid among several", payload, "t=1700000000,v1=00ff," + good[len("t=1700000000,"):] + ",v0=abc", secret, true},
+		{"tampered payload", payload + " ", good, secret, false},
+		{"wrong secret", payload, good, "whsec_other", false},
+		{"no secret configured", payload, good, "", false},
+		{"too old", payload, stripeHeader(secret, now.Add(-6*time.Minute), payload), secret, false},
+		{"from the future", payload, stripeHeader(secret, now.Add(6*time.Minute), payload), secret, false},
+		{"within tolerance", payload, stripeHeader(secret, now.Add(-4*time.Minute), payload), secret, true},
+		{"missing timestamp", payload, good[len("t=1700000000,"):], secret, false},
+		{"missing signature", payload, "t=1700000000", secret, false},
+		{"v0 only", payload, "t=1700000000,v0=" + good[len("t=1700000000,v1="):], secret, false},
+		{"empty header", payload, "", secret, false},
+	}
+	for _, c := range cases {
+		err := verifyStripeSignature([]byte(c.payload), c.header, c.secret, now)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
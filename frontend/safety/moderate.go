@@ -0,0 +1,97 @@
+package safety
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ElvanForge/lesson-forge/backend/prompts"
+	"github.com/ElvanForge/lesson-forge/backend/readability"
+)
+
+// Rules for generated content, in addition to RuleTopic.
+const (
+	RuleProfanity   Rule = "profanity"   // a word from profanity.txt
+	RuleUnsafe      Rule = "unsafe"      // content from unsafe_content.txt
+	RuleReadability Rule = "readability" // written well above the requested grade; advisory
+	RuleClassifier  Rule = "classifier"  // the moderation model failed it
+)
+
+// ReadingGradeSlack is how far above the requested grade the measured
+// reading level may go before it is noted. Lesson plans are written for
+// the teacher as much as the class, and the formulas bottom out around
+// grade 3, so a K-2 plan routinely measures several grades high: the
+// finding is advice for readability rewrites and reviewers, and never
+// fails content on its own.
+const ReadingGradeSlack = 4
+
+// Moderation is the verdict on one piece of generated content, stored
+// with the generation or version it belongs to.
+type Moderation struct {
+	Passed   bool      `json:"passed"`
+	Findings []Finding `json:"findings,omitempty"`
+	// ReadingGrade is the measured level of English content (see
+	// readability.Stats.Grade), and TargetGrade the requested grade when
+	// it is a K-12 one.
+	ReadingGrade float64 `json:"readingGrade,omitempty"`
+	TargetGrade  *int    `json:"targetGrade,omitempty"`
+	// Classifier is the moderation model's answer: pass, fail or unclear,
+	// error if the call failed, or empty when it wasn't asked.
+	Classifier string `json:"classifier,omitempty"`
+	// Attempts is how many generations it took to get this content.
+	Attempts int `json:"attempts"`
+}
+
+// Moderate checks content written for grade against the wordlists and,
+// for English content (locale "" or en-*), notes a reading level far above
+// the grade.
+func Moderate(content, grade, locale string) Moderation {
+	l := loadLists()
+	m := Moderation{Passed: true}
+	fail := func(rule Rule, detail string) {
+		m.Passed = false
+		m.Findings = append(m.Findings, Finding{Field: "content", Rule: rule, Detail: detail})
+	}
+	for _, t := range l.topics {
+		if t.re.MatchString(content) {
+			fail(RuleTopic, t.category)
+		}
+	}
+	for _, t := range l.unsafe {
+		if t.re.MatchString(content) {
+			fail(RuleUnsafe, t.category)
+		}
+	}
+	if w := l.profanity.FindString(content); w != "" {
+		fail(RuleProfanity, strings.ToLower(w))
+	}
+
+	if readability.English(locale) {
+		m.ReadingGrade = readability.Analyze(content).Grade
+		if target, ok := prompts.GradeLevel(grade); ok {
+			m.TargetGrade = &target
+			if readability.Compare(m.ReadingGrade, target, ReadingGradeSlack) == readability.Simplify {
+				m.Findings = append(m.Findings, Finding{Field: "content", Rule: RuleReadability,
+					Detail: fmt.Sprintf("reads at grade %.1f, requested %s", m.ReadingGrade, grade)})
+			}
+		}
+	}
+	return m
+}
+
+// AddClassifierReply records the moderation model's answer, which the
+// moderation prompt asks to be PASS or "FAIL: reason". Anything else is
+// noted as unclear and doesn't fail the content.
+func (m *Moderation) AddClassifierReply(reply string) {
+	line, _, _ := strings.Cut(strings.TrimSpace(reply), "\n")
+	word, reason, _ := strings.Cut(strings.TrimSpace(line), ":")
+	switch strings.ToUpper(strings.Trim(word, " *.")) {
+	case "PASS":
+		m.Classifier = "pass"
+	case "FAIL":
+		m.Classifier = "fail"
+		m.Passed = false
+		m.Findings = append(m.Findings, Finding{Field: "content", Rule: RuleClassifier, Detail: strings.TrimSpace(reason)})
+	default:
+		m.Classifier = "unclear"
+	}
+}
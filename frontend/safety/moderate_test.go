@@ -0,0 +1,159 @@
+package safety
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// k2Plan is a typical kindergarten plan: the children's parts are simple,
+// but the teacher-facing parts read far above grade K.
+const k2Plan = `# Lesson: Exploring the Five Senses
+## Objectives
+- Students will identify and categorize everyday objects using their five senses.
+- Students will demonstrate understanding through collaborative observation activities.
+- Students will communicate observations using descriptive vocabulary.
+## Summary of Tasks
+1. Introduction (5 minutes): Facilitate a whole-group discussion, activating prior knowledge about sensory experiences.
+2. Mystery Bag Exploration (10 minutes): Children investigate concealed objects, describing texture, temperature and approximate dimensions.
+3. Sensory Sorting Stations (10 minutes): Rotate small groups through differentiated stations incorporating manipulatives.
+4. Song: "I can see, I can hear, I can smell with my nose!"
+5. Closure: Children share one favorite sense. Formatively assess participation using an observation checklist.
+## Materials & Equipment
+- Opaque drawstring bags, assorted classroom objects, scented cotton balls, laminated picture cards
+- Observation checklist for documentation and differentiation
+## References
+- Early Childhood Science Standards, Physical Sciences: Properties of Materials
+## Take Home Tasks
+- Find one thing at home that is soft. Draw it!
+---
+*Generated by Vaelia Forge*`
+
+func TestModerate(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		grade   string
+		locale  string
+		passed  bool
+		rules   []Rule
+		detail  string // of the first finding, if set
+	}{
+		{name: "clean", content: "# Lesson: Fractions\nWe cut a pizza into four equal slices.", grade: "Grade 3", passed: true},
+		{name: "profanity", content: "This shit is hard.", grade: "Grade 8", rules: []Rule{RuleProfanity}, detail: "shit"},
+		{name: "profanity inflected", content: "Stop BITCHING about homework.", grade: "Grade 8", rules: []Rule{RuleProfanity}, detail: "bitching"},
+		{name: "profanity inside words", content: "Scunthorpe's class assessed the cocktail of shiitake and pistachio.", grade: "Grade 8", passed: true},
+		{name: "unsafe", content: "Never exceed the lethal dose listed on the label.", grade: "Grade 10", rules: []Rule{RuleUnsafe}, detail: "self_harm"},
+		{name: "topic", content: "Step 1: how to make a pipe bomb.", grade: "Grade 10", rules: []Rule{RuleTopic}, detail: "weapons"},
+		{name: "several", content: "Get drunk, then shit.", grade: "Grade 10", rules: []Rule{RuleUnsafe, RuleProfanity}},
+		{name: "kindergarten plan passes", content: k2Plan, grade: "Kindergarten", passed: true, rules: []Rule{RuleReadability}},
+		{name: "same plan at grade 9", content: k2Plan, grade: "Grade 9", passed: true},
+		{name: "not english", content: k2Plan, grade: "Kindergarten", locale: "es-MX", passed: true},
+	}
+	for _, c := range cases {
+		m := Moderate(c.content, c.grade, c.locale)
+		if m.Passed != c.passed {
+			t.Errorf("%s: Passed = %v, want %v (findings %+v)", c.name, m.Passed, c.passed, m.Findings)
+		}
+		var rules []Rule
+		for _, f := range m.Findings {
+			rules = append(rules, f.Rule)
+		}
+		if strings.Join(ruleStrings(rules), ",") != strings.Join(ruleStrings(c.rules), ",") {
+			t.Errorf("%s: rules %v, want %v", c.name, rules, c.rules)
+		}
+		if c.detail != "" && (len(m.Findings) == 0 || m.Findings[0].Detail != c.detail) {
+			t.Errorf("%s: findings %+v, want detail %q", c.name, m.Findings, c.detail)
+		}
+	}
+
+	m := Moderate(k2Plan, "Kindergarten", "en-US")
+	if m.ReadingGrade <= 7 || m.TargetGrade == nil || *m.TargetGrade != 0 {
+		t.Errorf("kindergarten plan: ReadingGrade %.1f, TargetGrade %v", m.ReadingGrade, m.TargetGrade)
+	}
+	if m := Moderate(k2Plan, "Kindergarten", "fr"); m.ReadingGrade != 0 || m.TargetGrade != nil {
+		t.Errorf("French content was measured: %+v", m)
+	}
+	if m := Moderate(k2Plan, "College", ""); m.TargetGrade != nil || len(m.Findings) != 0 {
+		t.Errorf("adult grade got a target: %+v", m)
+	}
+}
+
+func ruleStrings(rules []Rule) []string {
+	s := make([]string, len(rules))
+	for i, r := range rules {
+		s[i] = string(r)
+	}
+	return s
+}
+
+func TestAddClassifierReply(t *testing.T) {
+	cases := []struct {
+		reply      string
+		classifier string
+		passed     bool
+		detail     string
+	}{
+		{"PASS", "pass", true, ""},
+		{"**Pass.**\nThe lesson is fine.", "pass", true, ""},
+		{"FAIL: describes graphic violence", "fail", false, "describes graphic violence"},
+		{"fail:mentions gambling\nmore text", "fail", false, "mentions gambling"},
+		{"I think this is probably fine", "unclear", true, ""},
+		{"", "unclear", true, ""},
+	}
+	for _, c := range cases {
+		m := Moderation{Passed: true}
+		m.AddClassifierReply(c.reply)
+		if m.Classifier != c.classifier || m.Passed != c.passed {
+			t.Errorf("%q: classifier %q passed %v, want %q %v", c.reply, m.Classifier, m.Passed, c.classifier, c.passed)
+		}
+		if c.detail != "" && (len(m.Findings) != 1 || m.Findings[0].Detail != c.detail || m.Findings[0].Rule != RuleClassifier) {
+			t.Errorf("%q: findings %+v", c.reply, m.Findings)
+		}
+	}
+}
+
+// TestWordlists checks every list entry loads and matches what it is
+// meant to, and that ordinary lesson vocabulary matches nothing.
+func TestWordlists(t *testing.T) {
+	l := loadLists()
+	if len(l.injection) == 0 || len(l.topics) == 0 || len(l.unsafe) == 0 {
+		t.Fatalf("empty list: %d injection, %d topic and %d unsafe rules", len(l.injection), len(l.topics), len(l.unsafe))
+	}
+	categories := regexp.MustCompile(`^[a-z_]+$`)
+	for _, rules := range [][]topicRule{l.topics, l.unsafe} {
+		for _, r := range rules {
+			if !categories.MatchString(r.category) {
+				t.Errorf("category %q of %s is not a lowercase identifier", r.category, r.re)
+			}
+		}
+	}
+	for _, w := range listLines("lists/profanity.txt") {
+		for _, form := range []string{w, strings.ToUpper(w), w + "s", w + "ing"} {
+			if !l.profanity.MatchString("well " + form + " then") {
+				t.Errorf("profanity list misses %q", form)
+			}
+		}
+	}
+
+	harmless := []string{
+		"Sex education: puberty and reproduction for grade 8 health class.",
+		"Drug awareness week: why we never take medicine that isn't ours.",
+		"Suicide prevention: who to talk to when you feel sad.",
+		"World War II: the bombing of Pearl Harbor and its consequences.",
+		"Essex, Middlesex and Sussex are English counties.",
+		"Assess your classmates' passages; the cockpit of a bass boat.",
+		"Hitchcock's thriller and the Titanic's shipwreck.",
+		"Chemistry: how fertilizers like ammonium nitrate help crops grow.",
+	}
+	for _, text := range harmless {
+		for _, r := range append(append([]topicRule{}, l.topics...), l.unsafe...) {
+			if r.re.MatchString(text) {
+				t.Errorf("%s rule %s matches harmless %q", r.category, r.re, text)
+			}
+		}
+		if w := l.profanity.FindString(text); w != "" {
+			t.Errorf("profanity %q found in harmless %q", w, text)
+		}
+	}
+}
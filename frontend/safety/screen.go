@@ -0,0 +1,249 @@
+// Package safety screens what users send before it reaches a model, and
+// moderates what the model writes before it reaches a classroom. The
+// patterns it looks for live in lists/ and are embedded in the binary.
+package safety
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed lists/*.txt
+var listFiles embed.FS
+
+// Rule is the kind of problem a Finding reports.
+type Rule string
+
+const (
+	RuleInjection Rule = "injection" // an attempt to override our instructions
+	RuleTopic     Rule = "topic"     // a topic not fit for K-12
+	RuleLength    Rule = "length"    // longer than the field allows
+	RuleFormat    Rule = "format"    // line breaks or control characters where they don't belong
+)
+
+// Policy is what screening does with a request that has findings.
+type Policy string
+
+const (
+	// PolicyReject refuses the request.
+	PolicyReject Policy = "reject"
+	// PolicySanitize removes override phrases and control characters and
+	// truncates long fields, then lets the request through unless it
+	// names a disallowed topic.
+	PolicySanitize Policy = "sanitize"
+	// PolicyFlag lets the request through unchanged unless it names a
+	// disallowed topic.
+	PolicyFlag Policy = "flag"
+)
+
+// ValidPolicy reports whether p is a known policy.
+func ValidPolicy(p string) bool {
+	switch Policy(p) {
+	case PolicyReject, PolicySanitize, PolicyFlag:
+		return true
+	}
+	return false
+}
+
+// Field is one piece of user input. Screen may rewrite *Value when the
+// policy is PolicySanitize.
+type Field struct {
+	Name       string
+	Value      *string
+	Max        int  // in characters; 0 means no limit
+	SingleLine bool // grade, duration and the like
+	Required   bool // refuse if sanitizing leaves nothing
+}
+
+// Finding is one problem found in a field.
+type Finding struct {
+	Field  string `json:"field"`
+	Rule   Rule   `json:"rule"`
+	Detail string `json:"detail"` // the matched text or topic category
+}
+
+// Verdict is the outcome of screening a request.
+type Verdict struct {
+	Findings []Finding
+	// Action is "rejected", "sanitized" or "flagged", or "" when nothing
+	// was found.
+	Action string
+}
+
+// Blocked reports whether the request must be refused.
+func (v Verdict) Blocked() bool { return v.Action == "rejected" }
+
+// Reason explains a refusal in words fit for the user.
+func (v Verdict) Reason() string {
+	for _, rule := range []Rule{RuleTopic, RuleInjection, RuleLength, RuleFormat} {
+		for _, f := range v.Findings {
+			if f.Rule != rule {
+				continue
+			}
+			switch rule {
+			case RuleTopic:
+				return "this topic isn't appropriate for K-12 lessons"
+			case RuleInjection:
+				return "the " + f.Field + " looks like an attempt to change the AI's instructions"
+			case RuleLength:
+				return "the " + f.Field + " is too long"
+			case RuleFormat:
+				return "the " + f.Field + " must be a single line of text"
+			}
+		}
+	}
+	return "the request could not be accepted"
+}
+
+// Screener checks input against the embedded lists under one policy.
+type Screener struct {
+	Policy Policy
+}
+
+type topicRule struct {
+	category string
+	re       *regexp.Regexp
+}
+
+type lists struct {
+	injection []*regexp.Regexp
+	topics    []topicRule // k12_topics.txt
+	unsafe    []topicRule // unsafe_content.txt
+	profanity *regexp.Regexp
+}
+
+var loadLists = sync.OnceValue(func() lists {
+	var l lists
+	for _, line := range listLines("lists/injection.txt") {
+		l.injection = append(l.injection, regexp.MustCompile("(?i)"+line))
+	}
+	l.topics = topicRules("lists/k12_topics.txt")
+	l.unsafe = topicRules("lists/unsafe_content.txt")
+	words := listLines("lists/profanity.txt")
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	l.profanity = regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)(s|es|ed|er|ers|ing)?\b`)
+	return l
+})
+
+func topicRules(name string) []topicRule {
+	var rules []topicRule
+	for _, line := range listLines(name) {
+		category, expr, ok := strings.Cut(line, ":")
+		if !ok {
+			panic("safety: " + name + ": line without category: " + line)
+		}
+		rules = append(rules, topicRule{strings.TrimSpace(category), regexp.MustCompile("(?i)" + strings.TrimSpace(expr))})
+	}
+	return rules
+}
+
+// listLines returns the non-blank, non-comment lines of an embedded list.
+func listLines(name string) []string {
+	data, err := listFiles.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	var lines []string
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Screen checks every field and, under PolicySanitize, cleans them in
+// place. Disallowed topics can't be cleaned up and are refused under
+// every policy.
+func (s *Screener) Screen(fields ...Field) Verdict {
+	l := loadLists()
+	var v Verdict
+	blocked := false
+	for _, f := range fields {
+		val := *f.Value
+		if f.SingleLine && strings.ContainsFunc(val, isLineBreakOrControl) || !f.SingleLine && strings.ContainsFunc(val, isControl) {
+			v.Findings = append(v.Findings, Finding{Field: f.Name, Rule: RuleFormat})
+		}
+		for _, re := range l.injection {
+			if m := re.FindString(val); m != "" {
+				v.Findings = append(v.Findings, Finding{Field: f.Name, Rule: RuleInjection, Detail: strings.TrimSpace(m)})
+			}
+		}
+		for _, t := range l.topics {
+			if t.re.MatchString(val) {
+				v.Findings = append(v.Findings, Finding{Field: f.Name, Rule: RuleTopic, Detail: t.category})
+				blocked = true
+			}
+		}
+		if n := utf8.RuneCountInString(val); f.Max > 0 && n > f.Max {
+			v.Findings = append(v.Findings, Finding{Field: f.Name, Rule: RuleLength, Detail: fmt.Sprintf("%d > %d", n, f.Max)})
+		}
+	}
+	if len(v.Findings) == 0 {
+		return v
+	}
+
+	switch {
+	case blocked:
+		v.Action = "rejected"
+	case s.Policy == PolicyFlag:
+		v.Action = "flagged"
+	case s.Policy == PolicySanitize:
+		v.Action = "sanitized"
+		for _, f := range fields {
+			*f.Value = sanitize(*f.Value, f, l.injection)
+			if f.Required && *f.Value == "" {
+				v.Action = "rejected"
+			}
+		}
+	default:
+		v.Action = "rejected"
+	}
+	return v
+}
+
+var spaceRun = regexp.MustCompile(` {2,}`)
+
+func sanitize(val string, f Field, injection []*regexp.Regexp) string {
+	for _, re := range injection {
+		val = re.ReplaceAllString(val, " ")
+	}
+	if f.SingleLine {
+		val = strings.Map(func(r rune) rune {
+			if isLineBreakOrControl(r) {
+				return ' '
+			}
+			return r
+		}, val)
+	} else {
+		val = strings.Map(func(r rune) rune {
+			if isControl(r) {
+				return -1
+			}
+			return r
+		}, val)
+	}
+	val = strings.TrimSpace(spaceRun.ReplaceAllString(val, " "))
+	if f.Max > 0 && utf8.RuneCountInString(val) > f.Max {
+		val = strings.TrimSpace(string([]rune(val)[:f.Max]))
+	}
+	return val
+}
+
+func isControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r'
+}
+
+func isLineBreakOrControl(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+}
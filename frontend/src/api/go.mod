@@ -1,23 +0,0 @@
-module github.com/ElvanForge/lesson-forge/backend
-
-go 1.22.0
-
-require (
-	baliance.com/gooxml v1.0.1
-	github.com/jackc/pgx/v5 v5.8.0
-	github.com/johnfercher/maroto v1.0.0
-	github.com/joho/godotenv v1.5.1
-)
-
-require (
-	github.com/boombuler/barcode v1.0.1 // indirect
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/jackc/pgpassfile v1.0.0 // indirect
-	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
-	github.com/jackc/puddle/v2 v2.2.2 // indirect
-	github.com/jung-kurt/gofpdf v1.16.2 // indirect
-	github.com/ruudk/golang-pdf417 v0.0.0-20201230142125-a7e3863a1245 // indirect
-	github.com/stretchr/objx v0.5.2 // indirect
-	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/text v0.29.0 // indirect
-)
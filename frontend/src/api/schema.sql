@@ -1,29 +0,0 @@
--- Users table to track credit balance
-CREATE TABLE IF NOT EXISTS users (
-    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    email TEXT UNIQUE NOT NULL,
-    credit_balance INTEGER NOT NULL DEFAULT 0,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- Transactions table for Stripe payments
-CREATE TABLE IF NOT EXISTS transactions (
-    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id UUID REFERENCES users(id),
-    stripe_session_id TEXT UNIQUE NOT NULL,
-    amount_cents INTEGER NOT NULL,
-    credits_added INTEGER NOT NULL,
-    status TEXT NOT NULL, -- 'pending', 'completed', 'failed'
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
-
--- PPTX Generations log
-CREATE TABLE IF NOT EXISTS generations (
-    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id UUID REFERENCES users(id),
-    prompt TEXT NOT NULL,
-    file_path TEXT,
-    status TEXT NOT NULL, -- 'processing', 'completed', 'failed'
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
@@ -0,0 +1,40 @@
+import { Marked, type Token } from 'marked';
+
+// Lesson content is AI output steered by user prompts, and shared lessons
+// are shown to other people, so it must never reach the page as live HTML.
+// Raw HTML in the markdown is shown as text and links may only use safe
+// schemes.
+
+const escapes: Record<string, string> = { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' };
+
+function escapeHtml(text: string): string {
+    return text.replace(/[&<>"']/g, (c) => escapes[c]);
+}
+
+function safeUrl(href: string): boolean {
+    // Browsers ignore whitespace and control characters inside a scheme.
+    const scheme = href.replace(/[\u0000-\u0020\u007f]/g, '').match(/^([a-z][a-z0-9+.-]*):/i);
+    return !scheme || ['http', 'https', 'mailto'].includes(scheme[1].toLowerCase());
+}
+
+const marked = new Marked({
+    renderer: {
+        html({ text }) {
+            return escapeHtml(text);
+        }
+    },
+    walkTokens(token: Token) {
+        if ((token.type === 'link' || token.type === 'image') && !safeUrl(token.href)) {
+            token.href = '#';
+        }
+    }
+});
+
+// renderMarkdown turns lesson markdown into HTML that is safe for {@html}.
+// Every "---" becomes a section divider.
+export function renderMarkdown(markdown: string): string {
+    return markdown
+        .split('---')
+        .map((part) => marked.parse(part, { async: false }))
+        .join('<hr class="my-8" />');
+}